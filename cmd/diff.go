@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/punkwalker/karpenter-generate/pkg/apply"
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/diff"
+	"github.com/punkwalker/karpenter-generate/pkg/karpenteraws"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+var diffOpts *options.Options
+
+var diffCmd = &cobra.Command{
+	Use:          "diff",
+	Short:        "Compare generated resources with resources in the cluster",
+	SilenceUsage: true,
+	RunE:         runDiff,
+}
+
+func init() {
+	diffOpts = options.NewDiff(diffCmd)
+	AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, _ []string) (err error) {
+	if err := diffOpts.ParseDiff(); err != nil {
+		return err
+	}
+	if err := aws.Init(diffOpts); err != nil {
+		return err
+	}
+	ctx, cancel := commandContext(cmd, diffOpts)
+	defer cancel()
+	defer func() { err = errors.Join(contextError(ctx, diffOpts, err), aws.SaveRecording()) }()
+
+	source, err := newSource(ctx)
+	if err != nil {
+		return err
+	}
+
+	objs, err := karpenteraws.Generate(ctx, diffOpts)
+	if err != nil {
+		return err
+	}
+
+	diffs, err := diff.Diff(ctx, source, objs)
+	if err != nil {
+		return err
+	}
+	if drifted := diff.Print(cmd.OutOrStdout(), diffs); drifted > 0 {
+		return &DriftError{Drifted: drifted, Total: len(diffs)}
+	}
+	return nil
+}
+
+// DriftError is returned when generated resources differ from the cluster, the command exits with DriftExitCode
+type DriftError struct {
+	Drifted int
+	Total   int
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%d of %d resources differ from the cluster", e.Drifted, e.Total)
+}
+
+// Returns live resources of the manifests directory, or of the cluster when it is not set
+func newSource(ctx context.Context) (diff.Source, error) {
+	if diffOpts.LiveDir != "" {
+		return diff.NewDirSource(diffOpts.LiveDir)
+	}
+	client, _, err := apply.NewClients(ctx, diffOpts)
+	if err != nil {
+		return nil, err
+	}
+	return diff.NewClusterSource(client), nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/punkwalker/karpenter-generate/pkg/eksctl"
+	"github.com/punkwalker/karpenter-generate/pkg/karpenteraws"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+var eksctlOpts *options.Options
+
+var fromEksctlCmd = &cobra.Command{
+	Use:          "from-eksctl",
+	Short:        "Generate Karpenter Custom Resources from eksctl ClusterConfig file",
+	SilenceUsage: true,
+	RunE:         runFromEksctl,
+}
+
+func init() {
+	eksctlOpts = options.NewFromEksctl(fromEksctlCmd)
+	AddCommand(fromEksctlCmd)
+}
+
+func runFromEksctl(_ *cobra.Command, _ []string) error {
+	if err := eksctlOpts.ParseFromEksctl(); err != nil {
+		return err
+	}
+
+	cfg, err := eksctl.Load(eksctlOpts.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	nodeGroups, err := eksctl.NodeGroups(cfg, eksctlOpts.NodeRole)
+	if err != nil {
+		return err
+	}
+
+	objs, err := karpenteraws.GenerateFromNodeGroups(eksctlOpts, nodeGroups)
+	if err != nil {
+		return err
+	}
+	return writeOutput(eksctlOpts, objs)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+	"github.com/punkwalker/karpenter-generate/pkg/printers"
+)
+
+// Prints generated resources as manifests or Terraform, or writes them to the output directory as files or a Helm chart
+func writeOutput(o *options.Options, objs []runtime.Object) error {
+	format := printers.Output(o.Output)
+	switch {
+	case format == printers.HelmChart:
+		return printers.WriteHelmChart(o.OutputDir, objs)
+	case o.OutputDir != "":
+		return printers.WriteDir(o.OutputDir, format, objs, o.Overlays)
+	case format == printers.Terraform:
+		return printers.Print(printers.NewTerraformPrinter(o.TerraformVariables), objs)
+	}
+
+	printer, err := printers.NewPrinter(format)
+	if err != nil {
+		return err
+	}
+	return printers.Print(printer, objs)
+}
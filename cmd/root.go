@@ -1,16 +1,25 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/punkwalker/karpenter-generate/pkg/apply"
 	"github.com/punkwalker/karpenter-generate/pkg/aws"
 	"github.com/punkwalker/karpenter-generate/pkg/karpenteraws"
 	"github.com/punkwalker/karpenter-generate/pkg/options"
-	"github.com/punkwalker/karpenter-generate/pkg/printers"
 )
 
+// DriftExitCode is the exit code of diff when resources differ, errors exit with 1
+const DriftExitCode = 2
+
 var opts *options.Options
 
 var rootCmd = &cobra.Command{
@@ -28,7 +37,18 @@ func init() {
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	// AWS calls are cancelled on the first Ctrl-C, the second one exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	var drift *DriftError
+	if errors.As(err, &drift) {
+		os.Exit(DriftExitCode)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -38,20 +58,77 @@ func AddCommand(cmd *cobra.Command) {
 	rootCmd.AddCommand(cmd)
 }
 
-func run(_ *cobra.Command, _ []string) error {
-	aws.Init(opts)
+func run(cmd *cobra.Command, _ []string) (err error) {
 	if err := opts.Parse(); err != nil {
 		return err
 	}
+	if err := aws.Init(opts); err != nil {
+		return err
+	}
+	ctx, cancel := commandContext(cmd, opts)
+	defer cancel()
+	// Responses of failed runs are recorded too
+	defer func() { err = errors.Join(contextError(ctx, opts, err), aws.SaveRecording()) }()
+
+	objs, err := karpenteraws.Generate(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Apply {
+		return applyObjects(ctx, cmd, objs)
+	}
+	return writeOutput(opts, objs)
+}
+
+// Returns the context of the command, it is cancelled by Ctrl-C and after the timeout of the options
+func commandContext(cmd *cobra.Command, o *options.Options) (context.Context, context.CancelFunc) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if o.Timeout > 0 {
+		return context.WithTimeout(ctx, o.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
 
-	printer, err := printers.NewPrinter(printers.Output(opts.Output))
+// Explains errors of calls cancelled by the timeout or by Ctrl-C
+func contextError(ctx context.Context, o *options.Options, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("timed out after %s: %w", o.Timeout, err)
+	case context.Canceled:
+		return fmt.Errorf("interrupted: %w", err)
+	}
+	return err
+}
+
+// Server-side applies generated resources and prints the result of every object
+func applyObjects(ctx context.Context, cmd *cobra.Command, objs []runtime.Object) error {
+	applier, err := apply.NewApplierFromOptions(ctx, opts)
 	if err != nil {
 		return err
 	}
+	if err := applier.CheckCRDs(objs); err != nil {
+		return err
+	}
 
-	nodePools, nodeClasses, err := karpenteraws.Generate(opts)
+	reports, err := applier.Apply(ctx, objs)
 	if err != nil {
 		return err
 	}
-	return printers.Print(printer, nodePools, nodeClasses)
+	conflicts := 0
+	for _, report := range reports {
+		fmt.Fprintln(cmd.OutOrStdout(), report)
+		if report.Result == apply.Conflicted {
+			conflicts++
+		}
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d resources were not applied, their fields are managed by another field manager", conflicts)
+	}
+	return nil
 }
@@ -0,0 +1,205 @@
+package v1
+
+import (
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func (in *NodePool) DeepCopyInto(out *NodePool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *NodePool) DeepCopy() *NodePool {
+	if in == nil {
+		return nil
+	}
+	out := new(NodePool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *NodePool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *NodePoolSpec) DeepCopyInto(out *NodePoolSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	in.Disruption.DeepCopyInto(&out.Disruption)
+	if in.Limits != nil {
+		out.Limits = in.Limits.DeepCopy()
+	}
+	if in.Weight != nil {
+		out.Weight = new(int32)
+		*out.Weight = *in.Weight
+	}
+}
+
+func (in *Disruption) DeepCopyInto(out *Disruption) {
+	*out = *in
+	in.ConsolidateAfter.DeepCopyInto(&out.ConsolidateAfter)
+	if in.Budgets != nil {
+		out.Budgets = make([]Budget, len(in.Budgets))
+		for i := range in.Budgets {
+			in.Budgets[i].DeepCopyInto(&out.Budgets[i])
+		}
+	}
+}
+
+func (in *Budget) DeepCopyInto(out *Budget) {
+	*out = *in
+	if in.Reasons != nil {
+		out.Reasons = make([]DisruptionReason, len(in.Reasons))
+		copy(out.Reasons, in.Reasons)
+	}
+	if in.Schedule != nil {
+		out.Schedule = new(string)
+		*out.Schedule = *in.Schedule
+	}
+	if in.Duration != nil {
+		out.Duration = new(metav1.Duration)
+		*out.Duration = *in.Duration
+	}
+}
+
+func (in *NodeClaimTemplate) DeepCopyInto(out *NodeClaimTemplate) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *NodeClaimTemplateSpec) DeepCopyInto(out *NodeClaimTemplateSpec) {
+	*out = *in
+	out.Taints = copyTaints(in.Taints)
+	out.StartupTaints = copyTaints(in.StartupTaints)
+	if in.Requirements != nil {
+		out.Requirements = make([]sigkarpenter.NodeSelectorRequirementWithMinValues, len(in.Requirements))
+		for i := range in.Requirements {
+			in.Requirements[i].DeepCopyInto(&out.Requirements[i])
+		}
+	}
+	if in.NodeClassRef != nil {
+		out.NodeClassRef = new(NodeClassReference)
+		*out.NodeClassRef = *in.NodeClassRef
+	}
+	if in.TerminationGracePeriod != nil {
+		out.TerminationGracePeriod = new(metav1.Duration)
+		*out.TerminationGracePeriod = *in.TerminationGracePeriod
+	}
+	in.ExpireAfter.DeepCopyInto(&out.ExpireAfter)
+}
+
+func (in *EC2NodeClass) DeepCopyInto(out *EC2NodeClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+func (in *EC2NodeClass) DeepCopy() *EC2NodeClass {
+	if in == nil {
+		return nil
+	}
+	out := new(EC2NodeClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *EC2NodeClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
+	*out = *in
+	if in.SubnetSelectorTerms != nil {
+		out.SubnetSelectorTerms = make([]awskarpenter.SubnetSelectorTerm, len(in.SubnetSelectorTerms))
+		for i := range in.SubnetSelectorTerms {
+			in.SubnetSelectorTerms[i].DeepCopyInto(&out.SubnetSelectorTerms[i])
+		}
+	}
+	if in.SecurityGroupSelectorTerms != nil {
+		out.SecurityGroupSelectorTerms = make([]awskarpenter.SecurityGroupSelectorTerm, len(in.SecurityGroupSelectorTerms))
+		for i := range in.SecurityGroupSelectorTerms {
+			in.SecurityGroupSelectorTerms[i].DeepCopyInto(&out.SecurityGroupSelectorTerms[i])
+		}
+	}
+	out.AssociatePublicIPAddress = copyBool(in.AssociatePublicIPAddress)
+	if in.AMISelectorTerms != nil {
+		out.AMISelectorTerms = make([]AMISelectorTerm, len(in.AMISelectorTerms))
+		for i := range in.AMISelectorTerms {
+			in.AMISelectorTerms[i].DeepCopyInto(&out.AMISelectorTerms[i])
+		}
+	}
+	out.AMIFamily = copyString(in.AMIFamily)
+	out.UserData = copyString(in.UserData)
+	out.InstanceProfile = copyString(in.InstanceProfile)
+	if in.Tags != nil {
+		out.Tags = make(map[string]string, len(in.Tags))
+		for key, val := range in.Tags {
+			out.Tags[key] = val
+		}
+	}
+	out.Kubelet = in.Kubelet.DeepCopy()
+	if in.BlockDeviceMappings != nil {
+		out.BlockDeviceMappings = make([]*awskarpenter.BlockDeviceMapping, len(in.BlockDeviceMappings))
+		for i := range in.BlockDeviceMappings {
+			out.BlockDeviceMappings[i] = in.BlockDeviceMappings[i].DeepCopy()
+		}
+	}
+	if in.InstanceStorePolicy != nil {
+		out.InstanceStorePolicy = new(awskarpenter.InstanceStorePolicy)
+		*out.InstanceStorePolicy = *in.InstanceStorePolicy
+	}
+	out.DetailedMonitoring = copyBool(in.DetailedMonitoring)
+	out.MetadataOptions = in.MetadataOptions.DeepCopy()
+	out.Context = copyString(in.Context)
+}
+
+func (in *AMISelectorTerm) DeepCopyInto(out *AMISelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		out.Tags = make(map[string]string, len(in.Tags))
+		for key, val := range in.Tags {
+			out.Tags[key] = val
+		}
+	}
+}
+
+func copyTaints(in []corev1.Taint) []corev1.Taint {
+	if in == nil {
+		return nil
+	}
+	out := make([]corev1.Taint, len(in))
+	for i := range in {
+		in[i].DeepCopyInto(&out[i])
+	}
+	return out
+}
+
+func copyString(in *string) *string {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func copyBool(in *bool) *bool {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
@@ -0,0 +1,19 @@
+// Package v1 contains the subset of the Karpenter v1 APIs (karpenter.sh/v1 and
+// karpenter.k8s.aws/v1) that karpenter-generate produces. Types that did not change
+// shape between v1beta1 and v1 are reused from the upstream v1beta1 packages.
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	Group    = "karpenter.sh"
+	AWSGroup = "karpenter.k8s.aws"
+	Version  = "v1"
+)
+
+var (
+	SchemeGroupVersion    = schema.GroupVersion{Group: Group, Version: Version}
+	AWSSchemeGroupVersion = schema.GroupVersion{Group: AWSGroup, Version: Version}
+)
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+// EC2NodeClass is the karpenter.k8s.aws/v1 EC2NodeClass
+type EC2NodeClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EC2NodeClassSpec `json:"spec"`
+}
+
+type EC2NodeClassSpec struct {
+	SubnetSelectorTerms        []awskarpenter.SubnetSelectorTerm        `json:"subnetSelectorTerms"`
+	SecurityGroupSelectorTerms []awskarpenter.SecurityGroupSelectorTerm `json:"securityGroupSelectorTerms"`
+	AssociatePublicIPAddress   *bool                                    `json:"associatePublicIPAddress,omitempty"`
+	AMISelectorTerms           []AMISelectorTerm                        `json:"amiSelectorTerms"`
+	// AMIFamily is only needed when amiSelectorTerms do not use an alias
+	AMIFamily       *string           `json:"amiFamily,omitempty"`
+	UserData        *string           `json:"userData,omitempty"`
+	Role            string            `json:"role,omitempty"`
+	InstanceProfile *string           `json:"instanceProfile,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	// Kubelet moved from the NodePool template to the EC2NodeClass in v1
+	Kubelet             *sigkarpenter.KubeletConfiguration `json:"kubelet,omitempty"`
+	BlockDeviceMappings []*awskarpenter.BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
+	InstanceStorePolicy *awskarpenter.InstanceStorePolicy  `json:"instanceStorePolicy,omitempty"`
+	DetailedMonitoring  *bool                              `json:"detailedMonitoring,omitempty"`
+	MetadataOptions     *awskarpenter.MetadataOptions      `json:"metadataOptions,omitempty"`
+	Context             *string                            `json:"context,omitempty"`
+}
+
+// AMISelectorTerm adds the alias term (e.g. "al2023@latest") which replaces amiFamily for EKS optimized AMIs
+type AMISelectorTerm struct {
+	Alias string            `json:"alias,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+	ID    string            `json:"id,omitempty"`
+	Name  string            `json:"name,omitempty"`
+	Owner string            `json:"owner,omitempty"`
+}
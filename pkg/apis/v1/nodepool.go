@@ -0,0 +1,73 @@
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+type ConsolidationPolicy string
+
+const (
+	ConsolidationPolicyWhenEmpty                ConsolidationPolicy = "WhenEmpty"
+	ConsolidationPolicyWhenEmptyOrUnderutilized ConsolidationPolicy = "WhenEmptyOrUnderutilized"
+)
+
+type DisruptionReason string
+
+const (
+	DisruptionReasonUnderutilized DisruptionReason = "Underutilized"
+	DisruptionReasonEmpty         DisruptionReason = "Empty"
+	DisruptionReasonDrifted       DisruptionReason = "Drifted"
+)
+
+// NodePool is the karpenter.sh/v1 NodePool
+type NodePool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NodePoolSpec `json:"spec"`
+}
+
+type NodePoolSpec struct {
+	Template   NodeClaimTemplate   `json:"template"`
+	Disruption Disruption          `json:"disruption"`
+	Limits     sigkarpenter.Limits `json:"limits,omitempty"`
+	Weight     *int32              `json:"weight,omitempty"`
+}
+
+type Disruption struct {
+	// ConsolidateAfter is required in v1, "0s" disables the delay
+	ConsolidateAfter    sigkarpenter.NillableDuration `json:"consolidateAfter"`
+	ConsolidationPolicy ConsolidationPolicy           `json:"consolidationPolicy,omitempty"`
+	Budgets             []Budget                      `json:"budgets,omitempty"`
+}
+
+type Budget struct {
+	Reasons  []DisruptionReason `json:"reasons,omitempty"`
+	Nodes    string             `json:"nodes"`
+	Schedule *string            `json:"schedule,omitempty"`
+	Duration *metav1.Duration   `json:"duration,omitempty"`
+}
+
+type NodeClaimTemplate struct {
+	sigkarpenter.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NodeClaimTemplateSpec `json:"spec"`
+}
+
+type NodeClaimTemplateSpec struct {
+	Taints                 []corev1.Taint                                      `json:"taints,omitempty"`
+	StartupTaints          []corev1.Taint                                      `json:"startupTaints,omitempty"`
+	Requirements           []sigkarpenter.NodeSelectorRequirementWithMinValues `json:"requirements"`
+	NodeClassRef           *NodeClassReference                                 `json:"nodeClassRef"`
+	TerminationGracePeriod *metav1.Duration                                    `json:"terminationGracePeriod,omitempty"`
+	ExpireAfter            sigkarpenter.NillableDuration                       `json:"expireAfter,omitempty"`
+}
+
+// NodeClassReference replaces the v1beta1 apiVersion with the API group of the NodeClass
+type NodeClassReference struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Group string `json:"group"`
+}
@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"fmt"
+)
+
+// Validate checks the v1 NodePool constraints which are enforced by the Karpenter CRD
+func (in *NodePool) Validate() error {
+	ref := in.Spec.Template.Spec.NodeClassRef
+	if ref == nil || ref.Name == "" || ref.Kind == "" || ref.Group == "" {
+		return fmt.Errorf(`nodepool "%s": nodeClassRef requires "group", "kind" and "name"`, in.Name)
+	}
+	if in.Spec.Disruption.ConsolidationPolicy != ConsolidationPolicyWhenEmpty &&
+		in.Spec.Disruption.ConsolidationPolicy != ConsolidationPolicyWhenEmptyOrUnderutilized {
+		return fmt.Errorf(`nodepool "%s": invalid consolidationPolicy "%s"`, in.Name, in.Spec.Disruption.ConsolidationPolicy)
+	}
+	for _, req := range in.Spec.Template.Spec.Requirements {
+		if req.MinValues != nil && *req.MinValues > len(req.Values) && req.Operator == "In" {
+			return fmt.Errorf(`nodepool "%s": minValues of requirement "%s" is greater than the number of values`, in.Name, req.Key)
+		}
+	}
+	return nil
+}
+
+// Validate checks the v1 EC2NodeClass constraints which are enforced by the Karpenter CRD
+func (in *EC2NodeClass) Validate() error {
+	if len(in.Spec.SubnetSelectorTerms) == 0 {
+		return fmt.Errorf(`ec2nodeclass "%s": "subnetSelectorTerms" is required`, in.Name)
+	}
+	if len(in.Spec.SecurityGroupSelectorTerms) == 0 {
+		return fmt.Errorf(`ec2nodeclass "%s": "securityGroupSelectorTerms" is required`, in.Name)
+	}
+	if len(in.Spec.AMISelectorTerms) == 0 {
+		return fmt.Errorf(`ec2nodeclass "%s": "amiSelectorTerms" is required`, in.Name)
+	}
+	for _, term := range in.Spec.AMISelectorTerms {
+		if term.Alias != "" && len(in.Spec.AMISelectorTerms) > 1 {
+			return fmt.Errorf(`ec2nodeclass "%s": "alias" can not be combined with other amiSelectorTerms`, in.Name)
+		}
+		if term.Alias == "" && in.Spec.AMIFamily == nil {
+			return fmt.Errorf(`ec2nodeclass "%s": "amiFamily" is required when amiSelectorTerms do not use an alias`, in.Name)
+		}
+	}
+	if (in.Spec.Role == "") == (in.Spec.InstanceProfile == nil) {
+		return fmt.Errorf(`ec2nodeclass "%s": exactly one of "role" or "instanceProfile" is required`, in.Name)
+	}
+	return nil
+}
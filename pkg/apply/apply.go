@@ -0,0 +1,170 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+const FieldManager = "karpenter-generate"
+
+type Result string
+
+const (
+	Created    Result = "created"
+	Configured Result = "configured"
+	Unchanged  Result = "unchanged"
+	Conflicted Result = "conflicted"
+)
+
+// Resources of the Karpenter kinds which are generated
+var resources = map[string]string{
+	"NodePool":     "nodepools",
+	"EC2NodeClass": "ec2nodeclasses",
+}
+
+// Report is the outcome of applying one object
+type Report struct {
+	Resource schema.GroupResource
+	Name     string
+	Result   Result
+	// Conflict details when the object is conflicted
+	Message string
+}
+
+func (r Report) String() string {
+	if r.Message != "" {
+		return fmt.Sprintf("%s/%s %s: %s", r.Resource, r.Name, r.Result, r.Message)
+	}
+	return fmt.Sprintf("%s/%s %s", r.Resource, r.Name, r.Result)
+}
+
+// Applier server-side applies generated resources, fields owned by other managers are never overwritten
+type Applier struct {
+	client    dynamic.Interface
+	discovery discovery.DiscoveryInterface
+}
+
+func NewApplier(client dynamic.Interface, discovery discovery.DiscoveryInterface) *Applier {
+	return &Applier{client: client, discovery: discovery}
+}
+
+// CheckCRDs returns an error if the cluster does not serve the Karpenter resources of objs at their API version
+func (a *Applier) CheckCRDs(objs []runtime.Object) error {
+	gvrs, err := groupVersionResources(objs)
+	if err != nil {
+		return err
+	}
+	for _, gvr := range gvrs {
+		list, err := a.discovery.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if list != nil && lo.ContainsBy(list.APIResources, func(r metav1.APIResource) bool { return r.Name == gvr.Resource }) {
+			continue
+		}
+
+		served := a.servedVersions(gvr.Group)
+		if len(served) == 0 {
+			return fmt.Errorf(`Karpenter CRD "%s" is not installed, install Karpenter before applying`, gvr.GroupResource())
+		}
+		return fmt.Errorf(`Karpenter CRD "%s" does not serve "%s", installed CRDs serve %v (use "--api-version" to generate a served version)`,
+			gvr.GroupResource(), gvr.Version, served)
+	}
+	return nil
+}
+
+// Returns the versions of the API group served by the cluster
+func (a *Applier) servedVersions(group string) []string {
+	groups, err := a.discovery.ServerGroups()
+	if err != nil {
+		return nil
+	}
+	for _, g := range groups.Groups {
+		if g.Name == group {
+			return lo.Map(g.Versions, func(v metav1.GroupVersionForDiscovery, _ int) string { return v.Version })
+		}
+	}
+	return nil
+}
+
+// Apply server-side applies every object, conflicts are reported instead of returned as errors
+func (a *Applier) Apply(ctx context.Context, objs []runtime.Object) ([]Report, error) {
+	reports := []Report{}
+	for _, obj := range objs {
+		u, gvr, err := ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		client := a.client.Resource(gvr)
+		report := Report{Resource: gvr.GroupResource(), Name: u.GetName()}
+
+		resourceVersion := ""
+		existing, err := client.Get(ctx, u.GetName(), metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+		case err != nil:
+			return nil, err
+		default:
+			resourceVersion = existing.GetResourceVersion()
+		}
+
+		applied, err := client.Apply(ctx, u.GetName(), u, metav1.ApplyOptions{FieldManager: FieldManager})
+		switch {
+		case apierrors.IsConflict(err):
+			report.Result, report.Message = Conflicted, err.Error()
+		case err != nil:
+			return nil, fmt.Errorf(`failed to apply %s "%s": %w`, gvr.GroupResource(), u.GetName(), err)
+		case resourceVersion == "":
+			report.Result = Created
+		case applied.GetResourceVersion() == resourceVersion:
+			report.Result = Unchanged
+		default:
+			report.Result = Configured
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// ToUnstructured returns the apply configuration of a generated object and its resource, server populated fields are removed
+func ToUnstructured(obj runtime.Object) (*unstructured.Unstructured, schema.GroupVersionResource, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, schema.GroupVersionResource{}, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	unstructured.RemoveNestedField(u.Object, "status")
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+
+	gvk := u.GroupVersionKind()
+	resource, ok := resources[gvk.Kind]
+	if !ok {
+		return nil, schema.GroupVersionResource{}, fmt.Errorf(`unsupported kind "%s"`, gvk.Kind)
+	}
+	return u, gvk.GroupVersion().WithResource(resource), nil
+}
+
+// Returns the distinct resources of objs, sorted
+func groupVersionResources(objs []runtime.Object) ([]schema.GroupVersionResource, error) {
+	gvrs := map[string]schema.GroupVersionResource{}
+	for _, obj := range objs {
+		_, gvr, err := ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		gvrs[gvr.String()] = gvr
+	}
+	keys := lo.Keys(gvrs)
+	sort.Strings(keys)
+	return lo.Map(keys, func(key string, _ int) schema.GroupVersionResource { return gvrs[key] }), nil
+}
@@ -0,0 +1,163 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+var (
+	nodePoolGVR  = schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1beta1", Resource: "nodepools"}
+	nodeClassGVR = schema.GroupVersionResource{Group: "karpenter.k8s.aws", Version: "v1beta1", Resource: "ec2nodeclasses"}
+)
+
+func nodePool(name string, weight int32) *sigkarpenter.NodePool {
+	return &sigkarpenter.NodePool{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "karpenter.sh/v1beta1", Kind: "NodePool"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       sigkarpenter.NodePoolSpec{Weight: &weight},
+	}
+}
+
+func nodeClass(name string) *awskarpenter.EC2NodeClass {
+	return &awskarpenter.EC2NodeClass{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "karpenter.k8s.aws/v1beta1", Kind: "EC2NodeClass"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       awskarpenter.EC2NodeClassSpec{Role: "KarpenterNodeRole"},
+	}
+}
+
+// Returns a fake dynamic client which server-side applies like the API server, objects named "conflicted"
+// have fields managed by another field manager
+func fakeClient(t *testing.T, objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	existing := []runtime.Object{}
+	for _, obj := range objs {
+		u, _, err := ToUnstructured(obj)
+		assert.NoError(t, err)
+		u.SetResourceVersion("1")
+		existing = append(existing, u)
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		nodePoolGVR:  "NodePoolList",
+		nodeClassGVR: "EC2NodeClassList",
+	}, existing...)
+
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		applied := &unstructured.Unstructured{}
+		if err := json.Unmarshal(patch.GetPatch(), &applied.Object); err != nil {
+			return true, nil, err
+		}
+		if patch.GetName() == "conflicted" {
+			return true, nil, apierrors.NewConflict(patch.GetResource().GroupResource(), patch.GetName(),
+				errors.New(`Apply failed with 1 conflict: conflict with "kubectl": .spec.weight`))
+		}
+
+		obj, err := client.Tracker().Get(patch.GetResource(), "", patch.GetName())
+		if apierrors.IsNotFound(err) {
+			applied.SetResourceVersion("1")
+			return true, applied, client.Tracker().Create(patch.GetResource(), applied, "")
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		current, _ := json.Marshal(obj.(*unstructured.Unstructured).Object["spec"])
+		spec, _ := json.Marshal(applied.Object["spec"])
+		if string(current) == string(spec) {
+			return true, obj, nil
+		}
+		applied.SetResourceVersion("2")
+		return true, applied, client.Tracker().Update(patch.GetResource(), applied, "")
+	})
+	return client
+}
+
+func fakeDiscovery(resources ...*metav1.APIResourceList) *discoveryfake.FakeDiscovery {
+	return &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{Resources: resources}}
+}
+
+func TestApply(t *testing.T) {
+	client := fakeClient(t, nodePool("unchanged", 10), nodePool("configured", 10), nodePool("conflicted", 10))
+	applier := NewApplier(client, fakeDiscovery())
+
+	reports, err := applier.Apply(context.Background(), []runtime.Object{
+		nodePool("unchanged", 10),
+		nodePool("configured", 20),
+		nodePool("conflicted", 20),
+		nodeClass("created"),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Result{Unchanged, Configured, Conflicted, Created},
+		[]Result{reports[0].Result, reports[1].Result, reports[2].Result, reports[3].Result})
+	assert.Equal(t, "ec2nodeclasses.karpenter.k8s.aws/created created", reports[3].String())
+	assert.Contains(t, reports[2].Message, `conflict with "kubectl"`)
+
+	for _, action := range client.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			assert.NotContains(t, string(patch.GetPatch()), "status")
+			assert.NotContains(t, string(patch.GetPatch()), "creationTimestamp")
+		}
+	}
+	created, err := client.Resource(nodeClassGVR).Get(context.Background(), "created", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "KarpenterNodeRole", created.Object["spec"].(map[string]any)["role"])
+}
+
+func TestCheckCRDs(t *testing.T) {
+	v1beta1 := []*metav1.APIResourceList{
+		{GroupVersion: "karpenter.sh/v1beta1", APIResources: []metav1.APIResource{{Name: "nodepools", Kind: "NodePool"}}},
+		{GroupVersion: "karpenter.k8s.aws/v1beta1", APIResources: []metav1.APIResource{{Name: "ec2nodeclasses", Kind: "EC2NodeClass"}}},
+	}
+	v1NodePool := nodePool("default", 10)
+	v1NodePool.APIVersion = "karpenter.sh/v1"
+
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		objs      []runtime.Object
+		wantErr   string
+	}{
+		{
+			name:      "Installed CRDs",
+			resources: v1beta1,
+			objs:      []runtime.Object{nodePool("default", 10), nodeClass("default")},
+		},
+		{
+			name:      "Missing CRDs",
+			resources: nil,
+			objs:      []runtime.Object{nodePool("default", 10)},
+			wantErr:   `Karpenter CRD "nodepools.karpenter.sh" is not installed`,
+		},
+		{
+			name:      "Version not served",
+			resources: v1beta1,
+			objs:      []runtime.Object{v1NodePool},
+			wantErr:   `Karpenter CRD "nodepools.karpenter.sh" does not serve "v1", installed CRDs serve [v1beta1]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewApplier(fakeClient(t), fakeDiscovery(tt.resources...)).CheckCRDs(tt.objs)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package apply
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+// ClusterDescriber describes the EKS cluster the kubeconfig context is checked against,
+// DescribeCluster returns a nil cluster or an error when the cluster is not found
+type ClusterDescriber interface {
+	DescribeCluster(ctx context.Context, clusterName string) (*ekstypes.Cluster, error)
+}
+
+// NewApplierFromOptions returns an Applier of the kubeconfig in options, $KUBECONFIG or ~/.kube/config, or of
+// the EKS cluster endpoint authenticated with an IAM token of the AWS profile when there is no kubeconfig
+func NewApplierFromOptions(ctx context.Context, opts *options.Options) (*Applier, error) {
+	client, discoveryClient, err := NewClients(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewApplier(client, discoveryClient), nil
+}
+
+// NewClients returns dynamic and discovery clients of the cluster in options
+func NewClients(ctx context.Context, opts *options.Options) (dynamic.Interface, discovery.DiscoveryInterface, error) {
+	var eksClient ClusterDescriber = aws.NewEKSClient()
+	if opts.InputDir != "" {
+		fileClient, err := aws.NewFileClient(opts.InputDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		eksClient = fileClient
+	}
+	config, err := restConfig(ctx, opts, eksClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, discoveryClient, nil
+}
+
+// Returns the config of --kubeconfig, $KUBECONFIG or ~/.kube/config like kubectl, or of the EKS cluster endpoint
+// authenticated with an IAM token of the AWS profile when there is no kubeconfig.
+// The server of the context must be the endpoint of the EKS cluster unless the context is selected with --context
+func restConfig(ctx context.Context, opts *options.Options, eksClient ClusterDescriber) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: opts.KubeContext}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	config, err := clientConfig.ClientConfig()
+	if clientcmd.IsEmptyConfig(err) {
+		return eksRestConfig(ctx, opts, eksClient)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contextName := opts.KubeContext
+	if contextName == "" {
+		raw, err := clientConfig.RawConfig()
+		if err != nil {
+			return nil, err
+		}
+		contextName = raw.CurrentContext
+	}
+	fmt.Fprintf(os.Stderr, "Using kubeconfig context \"%s\" of server %s\n", contextName, config.Host)
+
+	cluster, err := eksClient.DescribeCluster(ctx, opts.ClusterName)
+	if err != nil {
+		return nil, aws.FormatErrorAsMessageOnly(err)
+	}
+	endpoint := ""
+	if cluster != nil {
+		endpoint = lo.FromPtr(cluster.Endpoint)
+	}
+	switch {
+	case endpoint == "":
+		warnings.Warnf(`endpoint of EKS cluster "%s" is not found, kubeconfig context "%s" is not checked`, opts.ClusterName, contextName)
+	case sameServer(config.Host, endpoint):
+	case opts.KubeContext != "":
+		warnings.Warnf(`server %s of kubeconfig context "%s" is not the endpoint %s of EKS cluster "%s"`, config.Host, contextName, endpoint, opts.ClusterName)
+	default:
+		return nil, fmt.Errorf(`server %s of kubeconfig context "%s" is not the endpoint %s of EKS cluster "%s", select the context of the cluster with "--context"`,
+			config.Host, contextName, endpoint, opts.ClusterName)
+	}
+	return config, nil
+}
+
+// Returns true when the kubeconfig server and the EKS endpoint are the same URL, EKS endpoints are upper case
+func sameServer(server, endpoint string) bool {
+	normalize := func(url string) string { return strings.TrimSuffix(strings.ToLower(url), "/") }
+	return normalize(server) == normalize(endpoint)
+}
+
+func eksRestConfig(ctx context.Context, opts *options.Options, eksClient ClusterDescriber) (*rest.Config, error) {
+	cluster, err := eksClient.DescribeCluster(ctx, opts.ClusterName)
+	if err != nil {
+		return nil, aws.FormatErrorAsMessageOnly(err)
+	}
+	if cluster == nil || cluster.Endpoint == nil || cluster.CertificateAuthority == nil || cluster.CertificateAuthority.Data == nil {
+		return nil, fmt.Errorf(`EKS cluster "%s" has no API server endpoint`, opts.ClusterName)
+	}
+	caData, err := base64.StdEncoding.DecodeString(*cluster.CertificateAuthority.Data)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid certificate authority of EKS cluster "%s": %w`, opts.ClusterName, err)
+	}
+	token, err := aws.GetToken(ctx, opts.ClusterName)
+	if err != nil {
+		return nil, aws.FormatErrorAsMessageOnly(err)
+	}
+	return &rest.Config{
+		Host:            *cluster.Endpoint,
+		BearerToken:     token,
+		TLSClientConfig: rest.TLSClientConfig{CAData: caData},
+	}, nil
+}
@@ -0,0 +1,84 @@
+package apply
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: env
+clusters:
+- name: env
+  cluster:
+    server: https://env.example.com
+- name: flag
+  cluster:
+    server: https://flag.example.com
+contexts:
+- name: env
+  context:
+    cluster: env
+- name: flag
+  context:
+    cluster: flag
+`
+
+// Serves the EKS clusters by name
+type fakeClusterDescriber map[string]*ekstypes.Cluster
+
+func (f fakeClusterDescriber) DescribeCluster(_ context.Context, clusterName string) (*ekstypes.Cluster, error) {
+	return f[clusterName], nil
+}
+
+func TestRestConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(file, []byte(kubeconfig), 0o600))
+
+	clusters := fakeClusterDescriber{
+		"env":   {Endpoint: lo.ToPtr("https://ENV.example.com")},
+		"other": {Endpoint: lo.ToPtr("https://other.example.com")},
+	}
+
+	tests := []struct {
+		name         string
+		env          string
+		opts         options.Options
+		expectedHost string
+		err          string
+	}{
+		{name: "$KUBECONFIG", env: file, opts: options.Options{ClusterName: "env"}, expectedHost: "https://env.example.com"},
+		{name: "Context of $KUBECONFIG", env: file, opts: options.Options{ClusterName: "env", KubeContext: "flag"}, expectedHost: "https://flag.example.com"},
+		{name: "--kubeconfig", opts: options.Options{ClusterName: "env", Kubeconfig: file}, expectedHost: "https://env.example.com"},
+		{name: "Context not found", env: file, opts: options.Options{ClusterName: "env", KubeContext: "other"}, err: `context "other" does not exist`},
+		{
+			name: "Current context of another cluster",
+			env:  file,
+			opts: options.Options{ClusterName: "other"},
+			err:  `server https://env.example.com of kubeconfig context "env" is not the endpoint https://other.example.com of EKS cluster "other"`,
+		},
+		{name: "Cluster not found", env: file, opts: options.Options{ClusterName: "missing"}, expectedHost: "https://env.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KUBECONFIG", tt.env)
+			config, err := restConfig(context.Background(), &tt.opts, clusters)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedHost, config.Host)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+type AutoScalingClient struct {
+	api AutoScalingAPI
+}
+
+func NewAutoScalingClient() *AutoScalingClient {
+	return NewAutoScalingClientFromAPI(autoscaling.NewFromConfig(GetConfig()))
+}
+
+// NewAutoScalingClientFromAPI returns an AutoScalingClient calling api instead of the Auto Scaling client of the shared config
+func NewAutoScalingClientFromAPI(api AutoScalingAPI) *AutoScalingClient {
+	return &AutoScalingClient{api: api}
+}
+
+// Describes Auto Scaling groups by name or by tags, all the tags must match
+func (c *AutoScalingClient) DescribeAutoScalingGroups(ctx context.Context, names []string, tags map[string]string) ([]types.AutoScalingGroup, error) {
+	groups := []types.AutoScalingGroup{}
+	input := autoscaling.DescribeAutoScalingGroupsInput{}
+
+	if len(names) > 0 {
+		input.AutoScalingGroupNames = names
+	}
+
+	for key, val := range tags {
+		input.Filters = append(input.Filters, types.Filter{
+			Name:   aws.String("tag:" + key),
+			Values: []string{val},
+		})
+	}
+
+	tokens := newPageTokens("DescribeAutoScalingGroups")
+	paginator := autoscaling.NewDescribeAutoScalingGroupsPaginator(c.api, &input)
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := tokens.next(out.NextToken); err != nil {
+			return nil, err
+		}
+		groups = append(groups, out.AutoScalingGroups...)
+	}
+
+	return groups, nil
+}
@@ -9,16 +9,20 @@ import (
 )
 
 type EC2Client struct {
-	*ec2.Client
+	api EC2API
 }
 
 func NewEC2Client() *EC2Client {
-	return &EC2Client{ec2.NewFromConfig(GetConfig())}
+	return NewEC2ClientFromAPI(ec2.NewFromConfig(GetConfig()))
+}
+
+// NewEC2ClientFromAPI returns an EC2Client calling api instead of the EC2 client of the shared config
+func NewEC2ClientFromAPI(api EC2API) *EC2Client {
+	return &EC2Client{api: api}
 }
 
 // Describes the specified Launch Template versions or all of your Launch Template versions.
-func (c *EC2Client) DescribeLaunchTemplateVersions(id, version string) ([]types.LaunchTemplateVersion, error) {
-	pageNum := 0
+func (c *EC2Client) DescribeLaunchTemplateVersions(ctx context.Context, id, version string) ([]types.LaunchTemplateVersion, error) {
 	versions := []types.LaunchTemplateVersion{}
 	input := ec2.DescribeLaunchTemplateVersionsInput{}
 
@@ -30,24 +34,26 @@ func (c *EC2Client) DescribeLaunchTemplateVersions(id, version string) ([]types.
 		input.Versions = []string{version}
 	}
 
-	paginator := ec2.NewDescribeLaunchTemplateVersionsPaginator(c.Client, &input)
+	tokens := newPageTokens("DescribeLaunchTemplateVersions")
+	paginator := ec2.NewDescribeLaunchTemplateVersionsPaginator(c.api, &input)
 
-	for paginator.HasMorePages() && pageNum < maxPages {
-		out, err := paginator.NextPage(context.Background())
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
+		if err := tokens.next(out.NextToken); err != nil {
+			return nil, err
+		}
 		versions = append(versions, out.LaunchTemplateVersions...)
-		pageNum++
 	}
 
 	return versions, nil
 }
 
-func (c *EC2Client) DescribeVolumes(id string) ([]types.Volume, error) {
+func (c *EC2Client) DescribeVolumes(ctx context.Context, id string) ([]types.Volume, error) {
 	filters := []types.Filter{}
 	volumes := []types.Volume{}
-	pageNum := 0
 
 	if id != "" {
 		filters = append(filters, types.Filter{
@@ -61,16 +67,85 @@ func (c *EC2Client) DescribeVolumes(id string) ([]types.Volume, error) {
 		input.Filters = filters
 	}
 
-	paginator := ec2.NewDescribeVolumesPaginator(c.Client, &input)
+	tokens := newPageTokens("DescribeVolumes")
+	paginator := ec2.NewDescribeVolumesPaginator(c.api, &input)
 
-	for paginator.HasMorePages() && pageNum < maxPages {
-		out, err := paginator.NextPage(context.Background())
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
+		if err := tokens.next(out.NextToken); err != nil {
+			return nil, err
+		}
 		volumes = append(volumes, out.Volumes...)
-		pageNum++
 	}
 
 	return volumes, nil
 }
+
+// Describes subnets by ID and subnets having any of the tag keys
+func (c *EC2Client) DescribeSubnets(ctx context.Context, ids []string, tagKeys []string) ([]types.Subnet, error) {
+	filters := []types.Filter{}
+	subnets := []types.Subnet{}
+
+	if len(ids) > 0 {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("subnet-id"),
+			Values: ids,
+		})
+	}
+	if len(tagKeys) > 0 {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("tag-key"),
+			Values: tagKeys,
+		})
+	}
+
+	input := ec2.DescribeSubnetsInput{}
+	if len(filters) > 0 {
+		input.Filters = filters
+	}
+
+	tokens := newPageTokens("DescribeSubnets")
+	paginator := ec2.NewDescribeSubnetsPaginator(c.api, &input)
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := tokens.next(out.NextToken); err != nil {
+			return nil, err
+		}
+		subnets = append(subnets, out.Subnets...)
+	}
+
+	return subnets, nil
+}
+
+// Describes the instance types, all the instance types when names is empty
+func (c *EC2Client) DescribeInstanceTypes(ctx context.Context, names []string) ([]types.InstanceTypeInfo, error) {
+	instanceTypes := []types.InstanceTypeInfo{}
+	input := ec2.DescribeInstanceTypesInput{}
+
+	for _, name := range names {
+		input.InstanceTypes = append(input.InstanceTypes, types.InstanceType(name))
+	}
+
+	tokens := newPageTokens("DescribeInstanceTypes")
+	paginator := ec2.NewDescribeInstanceTypesPaginator(c.api, &input)
+
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := tokens.next(out.NextToken); err != nil {
+			return nil, err
+		}
+		instanceTypes = append(instanceTypes, out.InstanceTypes...)
+	}
+
+	return instanceTypes, nil
+}
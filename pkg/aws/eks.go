@@ -9,35 +9,42 @@ import (
 )
 
 type EKSClient struct {
-	*eks.Client
+	api EKSAPI
 }
 
 func NewEKSClient() *EKSClient {
-	return &EKSClient{eks.NewFromConfig(GetConfig())}
+	return NewEKSClientFromAPI(eks.NewFromConfig(GetConfig()))
 }
 
-func (c *EKSClient) ListNodegroups(clusterName string) ([]string, error) {
+// NewEKSClientFromAPI returns an EKSClient calling api instead of the EKS client of the shared config
+func NewEKSClientFromAPI(api EKSAPI) *EKSClient {
+	return &EKSClient{api: api}
+}
+
+func (c *EKSClient) ListNodegroups(ctx context.Context, clusterName string) ([]string, error) {
 	nodegroupNames := []string{}
-	pageNum := 0
+	tokens := newPageTokens("ListNodegroups")
 
-	paginator := eks.NewListNodegroupsPaginator(c.Client, &eks.ListNodegroupsInput{
+	paginator := eks.NewListNodegroupsPaginator(c.api, &eks.ListNodegroupsInput{
 		ClusterName: aws.String(clusterName),
 	})
 
-	for paginator.HasMorePages() && pageNum < maxPages {
-		out, err := paginator.NextPage(context.Background())
+	for paginator.HasMorePages() {
+		out, err := paginator.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
+		if err := tokens.next(out.NextToken); err != nil {
+			return nil, err
+		}
 		nodegroupNames = append(nodegroupNames, out.Nodegroups...)
-		pageNum++
 	}
 
 	return nodegroupNames, nil
 }
 
-func (c *EKSClient) DescribeNodegroup(clusterName, nodegroupName string) (*types.Nodegroup, error) {
-	result, err := c.Client.DescribeNodegroup(context.Background(), &eks.DescribeNodegroupInput{
+func (c *EKSClient) DescribeNodegroup(ctx context.Context, clusterName, nodegroupName string) (*types.Nodegroup, error) {
+	result, err := c.api.DescribeNodegroup(ctx, &eks.DescribeNodegroupInput{
 		ClusterName:   aws.String(clusterName),
 		NodegroupName: aws.String(nodegroupName),
 	})
@@ -48,3 +55,15 @@ func (c *EKSClient) DescribeNodegroup(clusterName, nodegroupName string) (*types
 
 	return result.Nodegroup, nil
 }
+
+func (c *EKSClient) DescribeCluster(ctx context.Context, clusterName string) (*types.Cluster, error) {
+	result, err := c.api.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(clusterName),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Cluster, nil
+}
@@ -7,8 +7,6 @@ import (
 	"github.com/aws/smithy-go"
 )
 
-const maxPages = 10
-
 // Return cleaner error message for service API errors
 func FormatError(err error) error {
 	var ae smithy.APIError
@@ -20,6 +18,10 @@ func FormatError(err error) error {
 
 // Return cleaner error message for service API errors
 func FormatErrorAsMessageOnly(err error) error {
+	var notRecorded *NotRecordedError
+	if errors.As(err, &notRecorded) {
+		return notRecorded
+	}
 	var ae smithy.APIError
 	if err != nil && errors.As(err, &ae) {
 		return fmt.Errorf(ae.ErrorMessage())
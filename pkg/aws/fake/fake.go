@@ -0,0 +1,231 @@
+// Package fake serves the EKS, EC2, Auto Scaling and SSM APIs called by the AWS clients from fixtures held in memory
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+)
+
+var (
+	_ aws.EKSAPI         = &Backend{}
+	_ aws.EC2API         = &Backend{}
+	_ aws.AutoScalingAPI = &Backend{}
+	_ aws.SSMAPI         = &Backend{}
+)
+
+// Backend implements the AWS APIs of the clients, fixtures can be changed between calls to build scenarios
+type Backend struct {
+	*aws.Fixtures
+	// Errors returned instead of the response by operation name (e.g.: "DescribeNodegroup")
+	Errors map[string]error
+	// Operations answering with no items instead of the fixtures (e.g.: "DescribeLaunchTemplateVersions"), as if nothing was found
+	Empty map[string]bool
+	// Number of items per page of paginated operations, 0 returns all the items in one page
+	PageSize int
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// NewBackend returns a Backend serving the AWS CLI output saved in dir
+func NewBackend(dir string) (*Backend, error) {
+	fixtures, err := aws.LoadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewBackendFromFixtures(fixtures), nil
+}
+
+// NewBackendFromFixtures returns a Backend serving the fixtures
+func NewBackendFromFixtures(fixtures *aws.Fixtures) *Backend {
+	if fixtures.Parameters == nil {
+		fixtures.Parameters = map[string]string{}
+	}
+	return &Backend{Fixtures: fixtures, Errors: map[string]error{}, Empty: map[string]bool{}}
+}
+
+// Calls returns the number of calls of the operation, a call per page for paginated operations
+func (b *Backend) Calls(operation string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[operation]
+}
+
+// Records the call and returns the error of a done context or the error injected for the operation
+func (b *Backend) call(ctx context.Context, operation string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.calls == nil {
+		b.calls = map[string]int{}
+	}
+	b.calls[operation]++
+	return b.Errors[operation]
+}
+
+func (b *Backend) files() *aws.FileClient {
+	return aws.NewFileClientFromFixtures(b.Fixtures)
+}
+
+// Returns the page of items starting at the token and the token of the next page, nil for the last page
+func page[T any](items []T, token *string, size int) ([]T, *string, error) {
+	start := 0
+	if token != nil {
+		var err error
+		if start, err = strconv.Atoi(*token); err != nil || start < 0 || start > len(items) {
+			return nil, nil, &smithy.GenericAPIError{Code: "InvalidNextToken", Message: fmt.Sprintf(`invalid next token "%s"`, *token)}
+		}
+	}
+	if size <= 0 || start+size >= len(items) {
+		return items[start:], nil, nil
+	}
+	return items[start : start+size], lo.ToPtr(strconv.Itoa(start + size)), nil
+}
+
+func filterValues(filters []ec2types.Filter, name string) []string {
+	return lo.FlatMap(filters, func(filter ec2types.Filter, _ int) []string {
+		if lo.FromPtr(filter.Name) != name {
+			return nil
+		}
+		return filter.Values
+	})
+}
+
+func (b *Backend) ListNodegroups(ctx context.Context, params *eks.ListNodegroupsInput, _ ...func(*eks.Options)) (*eks.ListNodegroupsOutput, error) {
+	if err := b.call(ctx, "ListNodegroups"); err != nil {
+		return nil, err
+	}
+	names, _ := b.files().ListNodegroups(ctx, lo.FromPtr(params.ClusterName))
+	nodegroups, next, err := page(names, params.NextToken, b.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &eks.ListNodegroupsOutput{Nodegroups: nodegroups, NextToken: next}, nil
+}
+
+func (b *Backend) DescribeNodegroup(ctx context.Context, params *eks.DescribeNodegroupInput, _ ...func(*eks.Options)) (*eks.DescribeNodegroupOutput, error) {
+	if err := b.call(ctx, "DescribeNodegroup"); err != nil {
+		return nil, err
+	}
+	if b.Empty["DescribeNodegroup"] {
+		return &eks.DescribeNodegroupOutput{}, nil
+	}
+	nodegroup, err := b.files().DescribeNodegroup(ctx, lo.FromPtr(params.ClusterName), lo.FromPtr(params.NodegroupName))
+	if err != nil {
+		return nil, &ekstypes.ResourceNotFoundException{Message: lo.ToPtr(fmt.Sprintf("No node group found for name: %s.", lo.FromPtr(params.NodegroupName)))}
+	}
+	return &eks.DescribeNodegroupOutput{Nodegroup: nodegroup}, nil
+}
+
+func (b *Backend) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, _ ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	if err := b.call(ctx, "DescribeCluster"); err != nil {
+		return nil, err
+	}
+	if b.Empty["DescribeCluster"] {
+		return &eks.DescribeClusterOutput{}, nil
+	}
+	cluster, _ := b.files().DescribeCluster(ctx, lo.FromPtr(params.Name))
+	if cluster == nil {
+		return nil, &ekstypes.ResourceNotFoundException{Message: lo.ToPtr(fmt.Sprintf("No cluster found for name: %s.", lo.FromPtr(params.Name)))}
+	}
+	return &eks.DescribeClusterOutput{Cluster: cluster}, nil
+}
+
+func (b *Backend) DescribeLaunchTemplateVersions(ctx context.Context, params *ec2.DescribeLaunchTemplateVersionsInput, _ ...func(*ec2.Options)) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	if err := b.call(ctx, "DescribeLaunchTemplateVersions"); err != nil {
+		return nil, err
+	}
+	if b.Empty["DescribeLaunchTemplateVersions"] {
+		return &ec2.DescribeLaunchTemplateVersionsOutput{}, nil
+	}
+	versions, err := b.files().DescribeLaunchTemplateVersions(ctx, lo.FromPtr(params.LaunchTemplateId), strings.Join(params.Versions, ","))
+	if err != nil {
+		return nil, &smithy.GenericAPIError{Code: "InvalidLaunchTemplateId.VersionNotFound", Message: err.Error()}
+	}
+	versions, next, err := page(versions, params.NextToken, b.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeLaunchTemplateVersionsOutput{LaunchTemplateVersions: versions, NextToken: next}, nil
+}
+
+// Volumes are not saved as fixtures, no volumes are returned
+func (b *Backend) DescribeVolumes(ctx context.Context, _ *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	if err := b.call(ctx, "DescribeVolumes"); err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeVolumesOutput{}, nil
+}
+
+func (b *Backend) DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error) {
+	if err := b.call(ctx, "DescribeSubnets"); err != nil {
+		return nil, err
+	}
+	ids := append(lo.Without(params.SubnetIds), filterValues(params.Filters, "subnet-id")...)
+	subnets, _ := b.files().DescribeSubnets(ctx, ids, filterValues(params.Filters, "tag-key"))
+	subnets, next, err := page(subnets, params.NextToken, b.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeSubnetsOutput{Subnets: subnets, NextToken: next}, nil
+}
+
+func (b *Backend) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	if err := b.call(ctx, "DescribeInstanceTypes"); err != nil {
+		return nil, err
+	}
+	names := lo.Map(params.InstanceTypes, func(name ec2types.InstanceType, _ int) string { return string(name) })
+	instanceTypes, _ := b.files().DescribeInstanceTypes(ctx, names)
+	instanceTypes, next, err := page(instanceTypes, params.NextToken, b.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &ec2.DescribeInstanceTypesOutput{InstanceTypes: instanceTypes, NextToken: next}, nil
+}
+
+func (b *Backend) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, _ ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	if err := b.call(ctx, "DescribeAutoScalingGroups"); err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for _, filter := range params.Filters {
+		if key, ok := strings.CutPrefix(lo.FromPtr(filter.Name), "tag:"); ok && len(filter.Values) > 0 {
+			tags[key] = filter.Values[0]
+		}
+	}
+	groups, _ := b.files().DescribeAutoScalingGroups(ctx, params.AutoScalingGroupNames, tags)
+	groups, next, err := page(groups, params.NextToken, b.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: groups, NextToken: next}, nil
+}
+
+func (b *Backend) GetParameter(ctx context.Context, params *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	if err := b.call(ctx, "GetParameter"); err != nil {
+		return nil, err
+	}
+	name := lo.FromPtr(params.Name)
+	value, ok := b.Parameters[name]
+	if !ok {
+		return nil, &ssmtypes.ParameterNotFound{Message: lo.ToPtr(fmt.Sprintf("Parameter %s not found.", name))}
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Name: params.Name, Value: lo.ToPtr(value)}}, nil
+}
@@ -0,0 +1,261 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+)
+
+// FileClient serves EKS, EC2 and Auto Scaling responses from JSON saved with "aws eks describe-cluster", "aws eks describe-nodegroup",
+// "aws ec2 describe-launch-template-versions", "aws ec2 describe-subnets", "aws ec2 describe-instance-types",
+// "aws ssm get-parameters" and "aws autoscaling describe-auto-scaling-groups"
+type FileClient struct {
+	*Fixtures
+}
+
+// Fixtures holds AWS resources saved as AWS CLI output
+type Fixtures struct {
+	Clusters               []ekstypes.Cluster
+	Nodegroups             []ekstypes.Nodegroup
+	LaunchTemplateVersions []ec2types.LaunchTemplateVersion
+	Subnets                []ec2types.Subnet
+	InstanceTypes          []ec2types.InstanceTypeInfo
+	AutoScalingGroups      []autoscalingtypes.AutoScalingGroup
+	// Values of SSM parameters by name
+	Parameters map[string]string
+}
+
+type describeClusterOutput struct {
+	Cluster *ekstypes.Cluster `json:"cluster"`
+}
+
+type describeNodegroupOutput struct {
+	Nodegroup *ekstypes.Nodegroup `json:"nodegroup"`
+}
+
+type describeLaunchTemplateVersionsOutput struct {
+	LaunchTemplateVersions []ec2types.LaunchTemplateVersion `json:"LaunchTemplateVersions"`
+}
+
+type describeSubnetsOutput struct {
+	Subnets []ec2types.Subnet `json:"Subnets"`
+}
+
+type describeInstanceTypesOutput struct {
+	InstanceTypes []ec2types.InstanceTypeInfo `json:"InstanceTypes"`
+}
+
+type ssmParameter struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// Output of "aws ssm get-parameters" or "aws ssm get-parameter"
+type getParametersOutput struct {
+	Parameters []ssmParameter `json:"Parameters"`
+	Parameter  *ssmParameter  `json:"Parameter"`
+}
+
+type describeAutoScalingGroupsOutput struct {
+	AutoScalingGroups []autoscalingtypes.AutoScalingGroup `json:"AutoScalingGroups"`
+}
+
+// Returns a FileClient serving the JSON files in dir, dir must contain nodegroups or Auto Scaling groups
+func NewFileClient(dir string) (*FileClient, error) {
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(fixtures.Nodegroups) == 0 && len(fixtures.AutoScalingGroups) == 0 {
+		return nil, fmt.Errorf(`no "aws eks describe-nodegroup" or "aws autoscaling describe-auto-scaling-groups" output found in "%s"`, dir)
+	}
+	return NewFileClientFromFixtures(fixtures), nil
+}
+
+// NewFileClientFromFixtures returns a FileClient serving the fixtures
+func NewFileClientFromFixtures(fixtures *Fixtures) *FileClient {
+	return &FileClient{fixtures}
+}
+
+// LoadFixtures reads all the JSON files in dir, files are identified by their content instead of their name
+func LoadFixtures(dir string) (*Fixtures, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	fixtures := &Fixtures{Parameters: map[string]string{}}
+	for _, file := range files {
+		data, err := os.ReadFile(file) // #nosec G304
+		if err != nil {
+			return nil, err
+		}
+
+		cluster := describeClusterOutput{}
+		if err := json.Unmarshal(data, &cluster); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		if cluster.Cluster != nil {
+			fixtures.Clusters = append(fixtures.Clusters, *cluster.Cluster)
+			continue
+		}
+
+		ng := describeNodegroupOutput{}
+		if err := json.Unmarshal(data, &ng); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		if ng.Nodegroup != nil {
+			fixtures.Nodegroups = append(fixtures.Nodegroups, *ng.Nodegroup)
+			continue
+		}
+
+		lt := describeLaunchTemplateVersionsOutput{}
+		if err := json.Unmarshal(data, &lt); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		fixtures.LaunchTemplateVersions = append(fixtures.LaunchTemplateVersions, lt.LaunchTemplateVersions...)
+
+		subnets := describeSubnetsOutput{}
+		if err := json.Unmarshal(data, &subnets); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		fixtures.Subnets = append(fixtures.Subnets, subnets.Subnets...)
+
+		instanceTypes := describeInstanceTypesOutput{}
+		if err := json.Unmarshal(data, &instanceTypes); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		fixtures.InstanceTypes = append(fixtures.InstanceTypes, instanceTypes.InstanceTypes...)
+
+		params := getParametersOutput{}
+		if err := json.Unmarshal(data, &params); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		for _, param := range append(params.Parameters, lo.FromPtr(params.Parameter)) {
+			if param.Name != "" {
+				fixtures.Parameters[param.Name] = param.Value
+			}
+		}
+
+		asg := describeAutoScalingGroupsOutput{}
+		if err := json.Unmarshal(data, &asg); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+		fixtures.AutoScalingGroups = append(fixtures.AutoScalingGroups, asg.AutoScalingGroups...)
+	}
+	return fixtures, nil
+}
+
+// Returns nil when the cluster is not saved in the input directory, it is only needed for the cluster security group
+func (c *FileClient) DescribeCluster(_ context.Context, clusterName string) (*ekstypes.Cluster, error) {
+	for _, cluster := range c.Clusters {
+		if cluster.Name != nil && *cluster.Name == clusterName {
+			return &cluster, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *FileClient) ListNodegroups(_ context.Context, clusterName string) ([]string, error) {
+	nodegroupNames := []string{}
+	for _, ng := range c.Nodegroups {
+		if ng.ClusterName != nil && *ng.ClusterName == clusterName {
+			nodegroupNames = append(nodegroupNames, *ng.NodegroupName)
+		}
+	}
+	return nodegroupNames, nil
+}
+
+func (c *FileClient) DescribeNodegroup(_ context.Context, clusterName, nodegroupName string) (*ekstypes.Nodegroup, error) {
+	for _, ng := range c.Nodegroups {
+		if ng.ClusterName != nil && *ng.ClusterName == clusterName && *ng.NodegroupName == nodegroupName {
+			return &ng, nil
+		}
+	}
+	return nil, fmt.Errorf(`nodegroup "%s" of cluster "%s" not found in input directory`, nodegroupName, clusterName)
+}
+
+func (c *FileClient) DescribeLaunchTemplateVersions(_ context.Context, id, version string) ([]ec2types.LaunchTemplateVersion, error) {
+	versions := []ec2types.LaunchTemplateVersion{}
+	for _, ltv := range c.LaunchTemplateVersions {
+		if id != "" && (ltv.LaunchTemplateId == nil || *ltv.LaunchTemplateId != id) {
+			continue
+		}
+		versions = append(versions, ltv)
+	}
+
+	switch version {
+	case "":
+	case "$Default":
+		versions = lo.Filter(versions, func(ltv ec2types.LaunchTemplateVersion, _ int) bool {
+			return ltv.DefaultVersion != nil && *ltv.DefaultVersion
+		})
+	case "$Latest":
+		sort.Slice(versions, func(i, j int) bool {
+			return lo.FromPtr(versions[i].VersionNumber) > lo.FromPtr(versions[j].VersionNumber)
+		})
+		if len(versions) > 1 {
+			versions = versions[:1]
+		}
+	default:
+		versions = lo.Filter(versions, func(ltv ec2types.LaunchTemplateVersion, _ int) bool {
+			return ltv.VersionNumber != nil && strconv.FormatInt(*ltv.VersionNumber, 10) == version
+		})
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf(`version "%s" of launch template "%s" not found in input directory`, version, id)
+	}
+	return versions, nil
+}
+
+// Returns subnets matching any of the IDs and having any of the tag keys
+func (c *FileClient) DescribeSubnets(_ context.Context, ids []string, tagKeys []string) ([]ec2types.Subnet, error) {
+	return lo.Filter(c.Subnets, func(subnet ec2types.Subnet, _ int) bool {
+		if len(ids) > 0 && !lo.Contains(ids, lo.FromPtr(subnet.SubnetId)) {
+			return false
+		}
+		return len(tagKeys) == 0 || lo.ContainsBy(subnet.Tags, func(tag ec2types.Tag) bool {
+			return lo.Contains(tagKeys, lo.FromPtr(tag.Key))
+		})
+	}), nil
+}
+
+// Returns the instance types saved in the input directory, instance types which are not saved are not returned
+func (c *FileClient) DescribeInstanceTypes(_ context.Context, names []string) ([]ec2types.InstanceTypeInfo, error) {
+	return lo.Filter(c.InstanceTypes, func(info ec2types.InstanceTypeInfo, _ int) bool {
+		return len(names) == 0 || lo.Contains(names, string(info.InstanceType))
+	}), nil
+}
+
+// Returns the value of the parameter, empty when the parameter is not saved in the input directory
+func (c *FileClient) GetParameter(_ context.Context, name string) (string, error) {
+	return c.Parameters[name], nil
+}
+
+// Returns Auto Scaling groups matching any of the names and all of the tags
+func (c *FileClient) DescribeAutoScalingGroups(_ context.Context, names []string, tags map[string]string) ([]autoscalingtypes.AutoScalingGroup, error) {
+	return lo.Filter(c.AutoScalingGroups, func(asg autoscalingtypes.AutoScalingGroup, _ int) bool {
+		if len(names) > 0 && !lo.Contains(names, lo.FromPtr(asg.AutoScalingGroupName)) {
+			return false
+		}
+		for key, val := range tags {
+			if !lo.ContainsBy(asg.Tags, func(tag autoscalingtypes.TagDescription) bool {
+				return lo.FromPtr(tag.Key) == key && lo.FromPtr(tag.Value) == val
+			}) {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
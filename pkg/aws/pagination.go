@@ -0,0 +1,26 @@
+package aws
+
+import "fmt"
+
+// Tracks the tokens returned by the pages of a paginated operation, a token returned twice would
+// list the same pages again, the pagination stops with an error instead of returning incomplete results
+type pageTokens struct {
+	operation string
+	seen      map[string]bool
+}
+
+func newPageTokens(operation string) *pageTokens {
+	return &pageTokens{operation: operation, seen: map[string]bool{}}
+}
+
+// Returns an error when the token of the next page was already returned by a previous page
+func (t *pageTokens) next(token *string) error {
+	if token == nil || *token == "" {
+		return nil
+	}
+	if t.seen[*token] {
+		return fmt.Errorf(`%s returned the token "%s" of a previous page, results would be incomplete`, t.operation, *token)
+	}
+	t.seen[*token] = true
+	return nil
+}
@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+)
+
+// Returns a nodegroup per page, the token of the page after repeatAfter is the token of the first page
+type pagedAPI struct {
+	EKSAPI
+	pages       int
+	repeatAfter int
+}
+
+func (p *pagedAPI) nextToken(token *string) (int, *string) {
+	page := 0
+	if token != nil {
+		page, _ = strconv.Atoi(*token)
+	}
+	switch {
+	case page+1 >= p.pages:
+		return page, nil
+	case p.repeatAfter > 0 && page >= p.repeatAfter:
+		return page, aws.String("1")
+	}
+	return page, aws.String(strconv.Itoa(page + 1))
+}
+
+func (p *pagedAPI) ListNodegroups(ctx context.Context, params *eks.ListNodegroupsInput, _ ...func(*eks.Options)) (*eks.ListNodegroupsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	page, next := p.nextToken(params.NextToken)
+	return &eks.ListNodegroupsOutput{Nodegroups: []string{fmt.Sprintf("ng-%d", page)}, NextToken: next}, nil
+}
+
+func (p *pagedAPI) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, _ ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	page, next := p.nextToken(params.NextToken)
+	return &autoscaling.DescribeAutoScalingGroupsOutput{
+		AutoScalingGroups: []types.AutoScalingGroup{{AutoScalingGroupName: aws.String(fmt.Sprintf("asg-%d", page))}},
+		NextToken:         next,
+	}, nil
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name        string
+		pages       int
+		repeatAfter int
+		cancel      bool
+		err         string
+	}{
+		{name: "One page", pages: 1},
+		{name: "More than 10 pages", pages: 25},
+		{name: "Repeated token", pages: 25, repeatAfter: 12, err: "of a previous page, results would be incomplete"},
+		{name: "Cancelled context", pages: 25, cancel: true, err: context.Canceled.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &pagedAPI{pages: tt.pages, repeatAfter: tt.repeatAfter}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+
+			nodegroups, err := NewEKSClientFromAPI(api).ListNodegroups(ctx, "my-cluster")
+			groups, asgErr := NewAutoScalingClientFromAPI(api).DescribeAutoScalingGroups(ctx, nil, nil)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("EKSClient.ListNodegroups() error = %v, expected %s", err, tt.err)
+				}
+				if asgErr == nil || !strings.Contains(asgErr.Error(), tt.err) {
+					t.Errorf("AutoScalingClient.DescribeAutoScalingGroups() error = %v, expected %s", asgErr, tt.err)
+				}
+				return
+			}
+			if err != nil || asgErr != nil {
+				t.Fatalf("ListNodegroups() error = %v, DescribeAutoScalingGroups() error = %v", err, asgErr)
+			}
+			if len(nodegroups) != tt.pages {
+				t.Errorf("EKSClient.ListNodegroups() returned %d nodegroups, expected %d", len(nodegroups), tt.pages)
+			}
+			if len(groups) != tt.pages {
+				t.Errorf("AutoScalingClient.DescribeAutoScalingGroups() returned %d groups, expected %d", len(groups), tt.pages)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// RedactedAccountID replaces account IDs in recordings
+const RedactedAccountID = "000000000000"
+
+var (
+	accountIDRegex = regexp.MustCompile(`\b[0-9]{12}\b`)
+	accessKeyRegex = regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`)
+	// Base64 user data of launch templates in EC2 (XML) and JSON responses
+	userDataRegex = regexp.MustCompile(`(<userData>|"UserData"\s*:\s*")([A-Za-z0-9+/=\s]+)(</userData>|")`)
+	// Headers holding credentials or signatures, they are not recorded
+	redactedHeaders = []string{"Authorization", "X-Amz-Security-Token", "Set-Cookie"}
+)
+
+// Recording holds AWS API requests and responses of a run
+type Recording struct {
+	Region       string        `json:"region"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and its response, account IDs and access keys are redacted
+type Interaction struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Target       string      `json:"target,omitempty"`
+	RequestBody  string      `json:"requestBody,omitempty"`
+	StatusCode   int         `json:"statusCode"`
+	Header       http.Header `json:"header,omitempty"`
+	ResponseBody string      `json:"responseBody,omitempty"`
+}
+
+func redact(value string) string {
+	value = redactUserData(value)
+	value = accountIDRegex.ReplaceAllString(value, RedactedAccountID)
+	return accessKeyRegex.ReplaceAllString(value, "${1}REDACTEDREDACTED")
+}
+
+// Decodes, redacts and encodes again base64 user data, user data which is not base64 is redacted as-is
+func redactUserData(value string) string {
+	return userDataRegex.ReplaceAllStringFunc(value, func(match string) string {
+		groups := userDataRegex.FindStringSubmatch(match)
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(groups[2]), ""))
+		if err != nil {
+			return match
+		}
+		return groups[1] + base64.StdEncoding.EncodeToString([]byte(redact(string(decoded)))) + groups[3]
+	})
+}
+
+// Returns the redacted request URL, the host is omitted as it only depends on the region of the recording
+func requestURL(req *http.Request) string {
+	return redact(req.URL.RequestURI())
+}
+
+// Reads the body of the request and restores it for the next reader
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
+
+// Recorder is an HTTP transport saving the requests sent through it and their responses
+type Recorder struct {
+	base      aws.HTTPClient
+	mu        sync.Mutex
+	recording Recording
+}
+
+// NewRecorder returns a Recorder sending requests with the HTTP client of the AWS config
+func NewRecorder(base aws.HTTPClient, region string) *Recorder {
+	return &Recorder{base: base, recording: Recording{Region: region}}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.base.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := http.Header{}
+	for key, values := range resp.Header.Clone() {
+		for _, value := range values {
+			header.Add(key, redact(value))
+		}
+	}
+	for _, key := range redactedHeaders {
+		header.Del(key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recording.Interactions = append(r.recording.Interactions, Interaction{
+		Method:       req.Method,
+		URL:          requestURL(req),
+		Target:       req.Header.Get("X-Amz-Target"),
+		RequestBody:  redact(reqBody),
+		StatusCode:   resp.StatusCode,
+		Header:       header,
+		ResponseBody: redact(string(respBody)),
+	})
+	return resp, nil
+}
+
+// Save writes the recorded interactions to file
+func (r *Recorder) Save(file string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(r.recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o600)
+}
+
+// Replayer is an HTTP transport serving responses of a recording, every interaction is served once
+type Replayer struct {
+	file      string
+	mu        sync.Mutex
+	recording Recording
+	served    []bool
+}
+
+// LoadReplayer reads the recording saved in file
+func LoadReplayer(file string) (*Replayer, error) {
+	data, err := os.ReadFile(file) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	recording := Recording{}
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf(`failed to parse recording "%s": %w`, file, err)
+	}
+	return &Replayer{file: file, recording: recording, served: make([]bool, len(recording.Interactions))}, nil
+}
+
+// Region returns the region of the recording
+func (r *Replayer) Region() string {
+	return r.recording.Region
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	url, target, body := requestURL(req), req.Header.Get("X-Amz-Target"), redact(reqBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for idx, interaction := range r.recording.Interactions {
+		if r.served[idx] || interaction.Method != req.Method || interaction.URL != url ||
+			interaction.Target != target || interaction.RequestBody != body {
+			continue
+		}
+		r.served[idx] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(interaction.ResponseBody))),
+			ContentLength: int64(len(interaction.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, &NotRecordedError{Method: req.Method, URL: url, File: r.file}
+}
+
+// NotRecordedError is returned when a request is not found in the recording, it is not retried
+type NotRecordedError struct {
+	Method string
+	URL    string
+	File   string
+}
+
+func (e *NotRecordedError) Error() string {
+	return fmt.Sprintf(`request "%s %s" not found in recording "%s", record again with the same flags`, e.Method, e.URL, e.File)
+}
+
+// Retries recorded responses without waiting, requests which are not recorded are not retried
+func replayRetryer() aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		o.Retryables = append([]retry.IsErrorRetryable{retry.IsErrorRetryableFunc(func(err error) aws.Ternary {
+			var notRecorded *NotRecordedError
+			if errors.As(err, &notRecorded) {
+				return aws.FalseTernary
+			}
+			return aws.UnknownTernary
+		})}, o.Retryables...)
+	})
+}
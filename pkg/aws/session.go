@@ -4,21 +4,67 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/punkwalker/karpenter-generate/pkg/options"
 )
 
+// Attempts of throttled requests, nodegroups are described concurrently
+const maxAttempts = 10
+
 var awsConfig *aws.Config
 var profile string
 var region string
 var debug bool
+var recordFile string
+var recorder *Recorder
+var replayer *Replayer
+
+// HTTP client of the AWS clients, nil to use the SDK defaults
+var httpClient *http.Client
 
-func Init(opts *options.Options) {
+// Init sets the profile and region of the AWS config, and loads the recording replayed instead of calling AWS APIs
+func Init(opts *options.Options) error {
 	profile = opts.Profile
 	region = opts.Region
 	debug = opts.Debug
+	recordFile = opts.Record
+	if opts.Replay != "" {
+		var err error
+		if replayer, err = LoadReplayer(opts.Replay); err != nil {
+			return err
+		}
+		if region == "" {
+			region = replayer.Region()
+		}
+	}
+	return nil
+}
+
+// Slows down requests when they are throttled, the retry quota is disabled as concurrent requests would exhaust it
+func adaptiveRetryer() aws.Retryer {
+	return retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+		o.StandardOptions = append(o.StandardOptions, func(so *retry.StandardOptions) {
+			so.MaxAttempts = maxAttempts
+			so.RateLimiter = ratelimit.None
+		})
+	})
+}
+
+// SaveRecording writes AWS API requests and responses to the file of "--record" flag
+func SaveRecording() error {
+	if recorder == nil {
+		return nil
+	}
+	if err := recorder.Save(recordFile); err != nil {
+		return fmt.Errorf(`failed to save recording "%s": %w`, recordFile, err)
+	}
+	return nil
 }
 
 func GetConfig() aws.Config {
@@ -29,6 +75,7 @@ func GetConfig() aws.Config {
 	cfgOptions := []func(*config.LoadOptions) error{
 		config.WithSharedConfigProfile(profile),
 		config.WithRegion(region),
+		config.WithRetryer(adaptiveRetryer),
 	}
 
 	if debug {
@@ -39,11 +86,30 @@ func GetConfig() aws.Config {
 		)
 	}
 
+	// Credentials are not needed to replay responses, requests are still signed
+	if replayer != nil {
+		cfgOptions = append(cfgOptions,
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("REPLAY", "REPLAY", "")),
+		)
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.Background(), cfgOptions...)
 	if err != nil {
 		log.Fatal(fmt.Errorf("failed to create AWS config: %w", err))
 	}
 	region = cfg.Region
+
+	// The HTTP client is replaced after loading the config, loading options like a custom CA bundle require the default client
+	switch {
+	case replayer != nil:
+		httpClient = &http.Client{Transport: replayer}
+		cfg.HTTPClient = httpClient
+		cfg.Retryer = replayRetryer
+	case recordFile != "":
+		recorder = NewRecorder(cfg.HTTPClient, cfg.Region)
+		httpClient = &http.Client{Transport: recorder}
+		cfg.HTTPClient = httpClient
+	}
 	awsConfig = &cfg
 
 	return cfg
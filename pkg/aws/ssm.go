@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+type SSMClient struct {
+	api SSMAPI
+}
+
+func NewSSMClient() *SSMClient {
+	return NewSSMClientFromAPI(ssm.NewFromConfig(GetConfig()))
+}
+
+// NewSSMClientFromAPI returns an SSMClient calling api instead of the SSM client of the shared config
+func NewSSMClientFromAPI(api SSMAPI) *SSMClient {
+	return &SSMClient{api: api}
+}
+
+// Returns the value of the parameter, empty when the parameter does not exist
+func (c *SSMClient) GetParameter(ctx context.Context, name string) (string, error) {
+	out, err := c.api.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(name)})
+	var notFound *types.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if out.Parameter == nil {
+		return "", nil
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
@@ -0,0 +1,33 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const (
+	clusterIDHeader = "x-k8s-aws-id"
+	tokenPrefix     = "k8s-aws-v1."
+)
+
+// GetToken returns an EKS authentication token of the caller IAM identity (same as "aws eks get-token"),
+// the token is a presigned STS GetCallerIdentity request bound to the cluster
+func GetToken(ctx context.Context, clusterName string) (string, error) {
+	client := sts.NewPresignClient(sts.NewFromConfig(GetConfig()))
+	req, err := client.PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{},
+		func(o *sts.PresignOptions) {
+			o.ClientOptions = append(o.ClientOptions, func(o *sts.Options) {
+				o.APIOptions = append(o.APIOptions,
+					smithyhttp.AddHeaderValue(clusterIDHeader, clusterName),
+					smithyhttp.AddHeaderValue("X-Amz-Expires", "60"),
+				)
+			})
+		})
+	if err != nil {
+		return "", err
+	}
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(req.URL)), nil
+}
@@ -0,0 +1,136 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/punkwalker/karpenter-generate/pkg/apply"
+)
+
+// Kinds of the Karpenter resources which are generated
+var kinds = map[string]string{
+	"nodepools":      "NodePool",
+	"ec2nodeclasses": "EC2NodeClass",
+}
+
+// Change is a difference of one field, Live is nil for added fields and Generated is nil for removed fields
+type Change struct {
+	Path      string
+	Live      any
+	Generated any
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Live == nil:
+		return fmt.Sprintf("+ %s: %s", c.Path, compact(c.Generated))
+	case c.Generated == nil:
+		return fmt.Sprintf("- %s: %s", c.Path, compact(c.Live))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, compact(c.Live), compact(c.Generated))
+	}
+}
+
+// ObjectDiff holds the differences of a generated resource and the live resource of the same name
+type ObjectDiff struct {
+	Resource schema.GroupResource
+	Name     string
+	// The resource does not exist and would be created
+	Missing bool
+	Changes []Change
+}
+
+func (d ObjectDiff) Drifted() bool {
+	return d.Missing || len(d.Changes) > 0
+}
+
+// Diff compares every generated object with the live resource of the same name, live resources
+// which are not generated are ignored because they are not changed by applying generated resources
+func Diff(ctx context.Context, source Source, objs []runtime.Object) ([]ObjectDiff, error) {
+	diffs := []ObjectDiff{}
+	for _, obj := range objs {
+		generated, gvr, err := apply.ToUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		objDiff := ObjectDiff{Resource: gvr.GroupResource(), Name: generated.GetName()}
+
+		live, err := source.Get(ctx, gvr, generated.GetName())
+		if err != nil {
+			return nil, err
+		}
+		if live == nil {
+			objDiff.Missing = true
+			diffs = append(diffs, objDiff)
+			continue
+		}
+
+		generatedContent, err := normalize(generated)
+		if err != nil {
+			return nil, err
+		}
+		liveContent, err := normalize(live)
+		if err != nil {
+			return nil, err
+		}
+		objDiff.Changes = compare("", liveContent, generatedContent)
+		diffs = append(diffs, objDiff)
+	}
+	return diffs, nil
+}
+
+// Returns the changes of two decoded JSON values sorted by path, lists are compared as a whole
+func compare(path string, live, generated any) []Change {
+	liveMap, liveIsMap := live.(map[string]any)
+	generatedMap, generatedIsMap := generated.(map[string]any)
+	if !liveIsMap || !generatedIsMap {
+		if reflect.DeepEqual(live, generated) {
+			return nil
+		}
+		return []Change{{Path: path, Live: live, Generated: generated}}
+	}
+
+	changes := []Change{}
+	keys := lo.Uniq(append(lo.Keys(liveMap), lo.Keys(generatedMap)...))
+	sort.Strings(keys)
+	for _, key := range keys {
+		changes = append(changes, compare(lo.Ternary(path == "", key, path+"."+key), liveMap[key], generatedMap[key])...)
+	}
+	return changes
+}
+
+// Print writes the drifted resources and returns their number
+func Print(w io.Writer, diffs []ObjectDiff) int {
+	drifted := 0
+	for _, d := range diffs {
+		if !d.Drifted() {
+			continue
+		}
+		drifted++
+		if d.Missing {
+			fmt.Fprintf(w, "%s/%s: not found, would be created\n", d.Resource, d.Name)
+			continue
+		}
+		fmt.Fprintf(w, "%s/%s:\n", d.Resource, d.Name)
+		for _, change := range d.Changes {
+			fmt.Fprintf(w, "  %s\n", change)
+		}
+	}
+	return drifted
+}
+
+func compact(val any) string {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(data)
+}
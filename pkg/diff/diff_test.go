@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func nodePool(cpu string) *sigkarpenter.NodePool {
+	return &sigkarpenter.NodePool{
+		TypeMeta: metav1.TypeMeta{APIVersion: "karpenter.sh/v1beta1", Kind: "NodePool"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "default",
+			Annotations: map[string]string{"generated-by": "karpenter-migrate"},
+		},
+		Spec: sigkarpenter.NodePoolSpec{
+			Disruption: sigkarpenter.Disruption{
+				ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenUnderutilized,
+				ExpireAfter:         sigkarpenter.NillableDuration{Duration: lo.ToPtr(720 * time.Hour)},
+			},
+			Limits: sigkarpenter.Limits{corev1.ResourceCPU: resource.MustParse(cpu)},
+			Template: sigkarpenter.NodeClaimTemplate{
+				Spec: sigkarpenter.NodeClaimSpec{
+					NodeClassRef: &sigkarpenter.NodeClassReference{Name: "default"},
+					Requirements: []sigkarpenter.NodeSelectorRequirementWithMinValues{
+						{NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+							Key: corev1.LabelInstanceTypeStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"m5.large"},
+						}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func nodeClass(name string) *awskarpenter.EC2NodeClass {
+	return &awskarpenter.EC2NodeClass{
+		TypeMeta: metav1.TypeMeta{APIVersion: "karpenter.k8s.aws/v1beta1", Kind: "EC2NodeClass"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{"generated-by": "karpenter-migrate"},
+		},
+		Spec: awskarpenter.EC2NodeClassSpec{
+			AMIFamily:           lo.ToPtr(awskarpenter.AMIFamilyAL2),
+			Role:                "eks-node-role",
+			SubnetSelectorTerms: []awskarpenter.SubnetSelectorTerm{{ID: "subnet-1"}, {ID: "subnet-2"}},
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	source, err := NewDirSource("testdata/live")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		objs     []runtime.Object
+		expected string
+	}{
+		{
+			name: "Defaulted and status fields are ignored",
+			objs: []runtime.Object{nodePool("100")},
+		},
+		{
+			name: "Changed, added and missing resources",
+			objs: []runtime.Object{nodePool("200"), nodeClass("default"), nodeClass("gpu")},
+			expected: `nodepools.karpenter.sh/default:
+  ~ spec.limits.cpu: "100" -> "200"
+ec2nodeclasses.karpenter.k8s.aws/default:
+  ~ spec.subnetSelectorTerms: [{"id":"subnet-1"}] -> [{"id":"subnet-1"},{"id":"subnet-2"}]
+ec2nodeclasses.karpenter.k8s.aws/gpu: not found, would be created
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := Diff(context.Background(), source, tt.objs)
+			assert.NoError(t, err)
+
+			out := &bytes.Buffer{}
+			drifted := Print(out, diffs)
+			assert.Equal(t, tt.expected, out.String())
+			assert.Equal(t, tt.expected != "", drifted > 0)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	live := map[string]any{"spec": map[string]any{"weight": 10.0, "role": "a"}}
+	generated := map[string]any{"spec": map[string]any{"role": "b", "userData": "echo"}}
+
+	assert.Equal(t, []Change{
+		{Path: "spec.role", Live: "a", Generated: "b"},
+		{Path: "spec.userData", Generated: "echo"},
+		{Path: "spec.weight", Live: 10.0},
+	}, compare("", live, generated))
+}
@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Annotations set by Karpenter and kubectl on live resources
+var serverAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"karpenter.sh/nodepool-hash",
+	"karpenter.sh/nodepool-hash-version",
+	"karpenter.k8s.aws/ec2nodeclass-hash",
+	"karpenter.k8s.aws/ec2nodeclass-hash-version",
+}
+
+var metadataOptionsDefault = map[string]any{
+	"httpEndpoint":            "enabled",
+	"httpProtocolIPv6":        "disabled",
+	"httpPutResponseHopLimit": 2,
+	"httpTokens":              "required",
+}
+
+// Duration fields, compared as durations because "720h" and "720h0m0s" are equal
+var durationFields = []string{
+	"spec.disruption.consolidateAfter",
+	"spec.disruption.expireAfter",
+	"spec.template.spec.expireAfter",
+	"spec.template.spec.terminationGracePeriod",
+}
+
+var budgetsDefault = []any{map[string]any{"nodes": "10%"}}
+
+// Values defaulted by the Karpenter CRDs, keyed by apiVersion/kind and field path
+var defaults = map[string]map[string]any{
+	"karpenter.sh/v1beta1/NodePool": {
+		"spec.disruption.consolidationPolicy": "WhenUnderutilized",
+		"spec.disruption.expireAfter":         "720h0m0s",
+		"spec.disruption.budgets":             budgetsDefault,
+	},
+	"karpenter.sh/v1/NodePool": {
+		"spec.disruption.consolidationPolicy": "WhenEmptyOrUnderutilized",
+		"spec.disruption.consolidateAfter":    "0s",
+		"spec.disruption.budgets":             budgetsDefault,
+		"spec.template.spec.expireAfter":      "720h0m0s",
+	},
+	"karpenter.k8s.aws/v1beta1/EC2NodeClass": {
+		"spec.metadataOptions": metadataOptionsDefault,
+	},
+	"karpenter.k8s.aws/v1/EC2NodeClass": {
+		"spec.metadataOptions": metadataOptionsDefault,
+	},
+}
+
+// Returns a copy of obj without status, server populated metadata and fields equal to their CRD default,
+// generated and live resources are normalized the same way so that only meaningful differences remain
+func normalize(obj *unstructured.Unstructured) (map[string]any, error) {
+	content, err := roundTrip(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	normalized := content.(map[string]any)
+
+	delete(normalized, "status")
+	metadata := map[string]any{"name": obj.GetName()}
+	if labels := obj.GetLabels(); len(labels) > 0 {
+		metadata["labels"] = labels
+	}
+	annotations := obj.GetAnnotations()
+	for _, annotation := range serverAnnotations {
+		delete(annotations, annotation)
+	}
+	if len(annotations) > 0 {
+		metadata["annotations"] = annotations
+	}
+	if normalized["metadata"], err = roundTrip(metadata); err != nil {
+		return nil, err
+	}
+
+	for _, path := range durationFields {
+		fields := strings.Split(path, ".")
+		if val, found, _ := unstructured.NestedString(normalized, fields...); found {
+			if duration, err := time.ParseDuration(val); err == nil {
+				_ = unstructured.SetNestedField(normalized, duration.String(), fields...)
+			}
+		}
+	}
+	for path, val := range defaults[obj.GetAPIVersion()+"/"+obj.GetKind()] {
+		fields := strings.Split(path, ".")
+		current, found, _ := unstructured.NestedFieldNoCopy(normalized, fields...)
+		if !found {
+			continue
+		}
+		defaultVal, err := roundTrip(val)
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(current, defaultVal) {
+			unstructured.RemoveNestedField(normalized, fields...)
+		}
+	}
+	return prune(normalized).(map[string]any), nil
+}
+
+// Returns val as decoded JSON, numbers become float64 on both sides of the comparison
+func roundTrip(val any) (any, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Removes null values and empty maps, which are omitted by the API server
+func prune(val any) any {
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return val
+	}
+	for key, v := range obj {
+		v = prune(v)
+		if m, isMap := v.(map[string]any); v == nil || (isMap && len(m) == 0) {
+			delete(obj, key)
+			continue
+		}
+		obj[key] = v
+	}
+	return obj
+}
@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// Source returns live resources, nil is returned when the resource does not exist
+type Source interface {
+	Get(ctx context.Context, gvr schema.GroupVersionResource, name string) (*unstructured.Unstructured, error)
+}
+
+// ClusterSource gets live resources from the cluster
+type ClusterSource struct {
+	client dynamic.Interface
+}
+
+func NewClusterSource(client dynamic.Interface) *ClusterSource {
+	return &ClusterSource{client: client}
+}
+
+func (s *ClusterSource) Get(ctx context.Context, gvr schema.GroupVersionResource, name string) (*unstructured.Unstructured, error) {
+	obj, err := s.client.Resource(gvr).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`failed to get %s "%s": %w`, gvr.GroupResource(), name, err)
+	}
+	return obj, nil
+}
+
+// DirSource serves live resources from YAML or JSON manifests, such as "kubectl get nodepools -o yaml" output
+type DirSource struct {
+	objects map[string]*unstructured.Unstructured
+}
+
+// Reads all the manifests in dir, a file can hold multiple documents or a List
+func NewDirSource(dir string) (*DirSource, error) {
+	files := []string{}
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	source := &DirSource{objects: map[string]*unstructured.Unstructured{}}
+	for _, file := range files {
+		if err := source.load(file); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+		}
+	}
+	return source, nil
+}
+
+func (s *DirSource) load(file string) error {
+	f, err := os.Open(file) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if !obj.IsList() {
+			s.objects[key(obj.GroupVersionKind().GroupVersion(), obj.GetKind(), obj.GetName())] = obj
+			continue
+		}
+		if err := obj.EachListItem(func(item runtime.Object) error {
+			u := item.(*unstructured.Unstructured)
+			s.objects[key(u.GroupVersionKind().GroupVersion(), u.GetKind(), u.GetName())] = u
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *DirSource) Get(_ context.Context, gvr schema.GroupVersionResource, name string) (*unstructured.Unstructured, error) {
+	return s.objects[key(gvr.GroupVersion(), kinds[gvr.Resource], name)], nil
+}
+
+func key(gv schema.GroupVersion, kind, name string) string {
+	return gv.String() + "/" + kind + "/" + name
+}
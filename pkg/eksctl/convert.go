@@ -0,0 +1,490 @@
+package eksctl
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	awskarpenterprovider "github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/punkwalker/karpenter-generate/pkg/karpenteraws"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+const (
+	ClusterNameTagKey       string = "alpha.eksctl.io/cluster-name"
+	PrivateSubnetTagKey     string = "kubernetes.io/role/internal-elb"
+	PublicSubnetTagKey      string = "kubernetes.io/role/elb"
+	DefaultInstanceType     string = "m5.large"
+	DefaultVolumeSize       int32  = 80
+	DefaultVolumeType       string = "gp3"
+	MixedInstanceType       string = "mixed"
+	userDataBoundary        string = "//"
+	bootstrapCommandWarning string = `nodegroup "%s": overrideBootstrapCommand is not migrated, Karpenter bootstraps nodes of the AMI family`
+)
+
+// Graviton instance families have a "g" after the generation (e.g.: m6g, c7gn, t4g)
+var armInstanceTypeRegex = regexp.MustCompile(`^[a-z]+[0-9]+[a-z]*g[a-z]*\.`)
+
+// Returns NodeGroups for all the nodeGroups and managedNodeGroups of the ClusterConfig,
+// role is used for nodegroups which do not specify an instance role or instance profile
+func NodeGroups(cfg *ClusterConfig, role string) ([]*karpenteraws.NodeGroup, error) {
+	karpenteraws.ClusterTag = map[string]string{
+		karpenteraws.ClusterTagKey + cfg.Metadata.Name: "owned",
+	}
+
+	nodeGroups := []*karpenteraws.NodeGroup{}
+	for _, ng := range cfg.NodeGroups {
+		nodeGroup, err := cfg.nodeGroup(ng, false, role)
+		if err != nil {
+			return nil, err
+		}
+		nodeGroups = append(nodeGroups, nodeGroup)
+	}
+	for _, ng := range cfg.ManagedNodeGroups {
+		nodeGroup, err := cfg.nodeGroup(ng, true, role)
+		if err != nil {
+			return nil, err
+		}
+		nodeGroups = append(nodeGroups, nodeGroup)
+	}
+	return nodeGroups, nil
+}
+
+func (c *ClusterConfig) nodeGroup(ng NodeGroup, managed bool, role string) (*karpenteraws.NodeGroup, error) {
+	if ng.Name == "" {
+		return nil, fmt.Errorf("nodegroup without name found in ClusterConfig")
+	}
+
+	instanceTypes := ng.instanceTypes()
+	amiType, err := ng.amiType(c.Metadata.Version, instanceTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets, subnetTags, err := c.subnets(ng)
+	if err != nil {
+		return nil, err
+	}
+
+	kubelet, err := ng.kubelet()
+	if err != nil {
+		return nil, err
+	}
+
+	eksNG := &ekstypes.Nodegroup{
+		NodegroupName: lo.ToPtr(ng.Name),
+		ClusterName:   lo.ToPtr(c.Metadata.Name),
+		Status:        ekstypes.NodegroupStatusActive,
+		AmiType:       amiType,
+		CapacityType:  ekstypes.CapacityTypesOnDemand,
+		InstanceTypes: instanceTypes,
+		Subnets:       subnets,
+		Labels:        ng.Labels,
+		Taints:        ng.taints(),
+		Tags:          ng.Tags,
+	}
+	if managed && ng.Spot {
+		eksNG.CapacityType = ekstypes.CapacityTypesSpot
+	}
+	if managed && ng.UpdateConfig != nil {
+		eksNG.UpdateConfig = &ekstypes.NodegroupUpdateConfig{
+			MaxUnavailable:           int32Ptr(ng.UpdateConfig.MaxUnavailable),
+			MaxUnavailablePercentage: int32Ptr(ng.UpdateConfig.MaxUnavailablePercentage),
+		}
+	}
+	if ng.MaxSize != nil {
+		eksNG.ScalingConfig = &ekstypes.NodegroupScalingConfig{
+			MinSize:     int32Ptr(ng.MinSize),
+			MaxSize:     int32Ptr(ng.MaxSize),
+			DesiredSize: int32Ptr(ng.DesiredCapacity),
+		}
+	}
+
+	userData, err := ng.userData(amiType)
+	if err != nil {
+		return nil, err
+	}
+
+	lt := &ec2types.ResponseLaunchTemplateData{
+		BlockDeviceMappings: ng.blockDeviceMappings(amiType),
+		MetadataOptions:     ng.metadataOptions(),
+		UserData:            userData,
+	}
+	if strings.HasPrefix(ng.AMI, "ami-") {
+		lt.ImageId = lo.ToPtr(ng.AMI)
+	}
+	if ng.SecurityGroups != nil {
+		lt.SecurityGroupIds = ng.SecurityGroups.AttachIDs
+	}
+
+	switch {
+	case ng.IAM != nil && ng.IAM.InstanceRoleARN != "":
+		eksNG.NodeRole = lo.ToPtr(ng.IAM.InstanceRoleARN)
+	case ng.IAM != nil && ng.IAM.InstanceProfileARN != "":
+		lt.IamInstanceProfile = &ec2types.LaunchTemplateIamInstanceProfileSpecification{
+			Arn: lo.ToPtr(ng.IAM.InstanceProfileARN),
+		}
+	case role != "":
+		eksNG.NodeRole = lo.ToPtr(role)
+	default:
+		return nil, fmt.Errorf(`nodegroup "%s" has no "iam.instanceRoleARN" or "iam.instanceProfileARN", specify the node role with "--role" flag`, ng.Name)
+	}
+
+	ng.warnUnsupported()
+
+	nodeGroup := &karpenteraws.NodeGroup{
+		Nodegroup:            eksNG,
+		CustomLT:             lt,
+		Kubelet:              kubelet,
+		InstanceRequirements: ng.instanceRequirements(),
+		SubnetTags:           subnetTags,
+	}
+
+	// Self-managed nodegroups can mix On-Demand and Spot instances
+	if !managed && ng.InstancesDistribution != nil {
+		nodeGroup.AutoScalingGroup = &autoscalingtypes.AutoScalingGroup{
+			AutoScalingGroupName: lo.ToPtr(ng.Name),
+			MixedInstancesPolicy: &autoscalingtypes.MixedInstancesPolicy{
+				InstancesDistribution: &autoscalingtypes.InstancesDistribution{
+					OnDemandBaseCapacity:                int32Ptr(ng.InstancesDistribution.OnDemandBaseCapacity),
+					OnDemandPercentageAboveBaseCapacity: int32Ptr(ng.InstancesDistribution.OnDemandPercentageAboveBaseCapacity),
+				},
+			},
+		}
+	}
+
+	// Bottlerocket settings are moved to the NodePool the same way as launch template user data
+	if err := nodeGroup.MigrateUserData(""); err != nil {
+		return nil, err
+	}
+	return nodeGroup, nil
+}
+
+// Returns instance types of the nodegroup, instances selected by attributes do not have instance types
+func (ng NodeGroup) instanceTypes() []string {
+	if ng.InstanceSelector != nil {
+		return nil
+	}
+
+	instanceTypes := lo.Filter(append([]string{ng.InstanceType}, ng.InstanceTypes...), func(it string, _ int) bool {
+		return it != "" && it != MixedInstanceType
+	})
+	if ng.InstancesDistribution != nil {
+		instanceTypes = append(instanceTypes, ng.InstancesDistribution.InstanceTypes...)
+	}
+	if len(instanceTypes) == 0 {
+		return []string{DefaultInstanceType}
+	}
+	return lo.Uniq(instanceTypes)
+}
+
+// Returns instance-cpu, instance-memory and instance-gpu-count requirements of the instance selector
+func (ng NodeGroup) instanceRequirements() []sigkarpenter.NodeSelectorRequirementWithMinValues {
+	if ng.InstanceSelector == nil {
+		return nil
+	}
+
+	reqs := []sigkarpenter.NodeSelectorRequirementWithMinValues{}
+	addReq := func(key, value string) {
+		reqs = append(reqs, sigkarpenter.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{value},
+			},
+		})
+	}
+
+	if ng.InstanceSelector.VCPUs > 0 {
+		addReq(awskarpenter.LabelInstanceCPU, strconv.Itoa(ng.InstanceSelector.VCPUs))
+	}
+	if memory := memoryMiB(ng.InstanceSelector.Memory); memory > 0 {
+		addReq(awskarpenter.LabelInstanceMemory, strconv.FormatInt(memory, 10))
+	}
+	if gpus := lo.FromPtr(ng.InstanceSelector.GPUs); gpus > 0 {
+		addReq(awskarpenter.LabelInstanceGPUCount, strconv.Itoa(gpus))
+	}
+	return reqs
+}
+
+// Instance selector memory is a quantity (e.g.: 16GiB) or a number of GiB
+func memoryMiB(memory string) int64 {
+	if memory == "" {
+		return 0
+	}
+	if gib, err := strconv.ParseFloat(memory, 64); err == nil {
+		return int64(gib * 1024)
+	}
+	quantity, err := resource.ParseQuantity(strings.TrimSuffix(memory, "B"))
+	if err != nil {
+		return 0
+	}
+	return quantity.Value() / (1024 * 1024)
+}
+
+// Returns the EKS AMI type matching the AMI family and architecture of the nodegroup
+func (ng NodeGroup) amiType(version string, instanceTypes []string) (ekstypes.AMITypes, error) {
+	arm := lo.ContainsBy(instanceTypes, func(it string) bool {
+		return armInstanceTypeRegex.MatchString(it)
+	})
+	if ng.InstanceSelector != nil {
+		arm = ng.InstanceSelector.CPUArchitecture == "arm64"
+	}
+
+	amiFamily := ng.AMIFamily
+	if amiFamily == "" {
+		// eksctl defaults to AmazonLinux2023 from Kubernetes 1.30
+		amiFamily = "AmazonLinux2"
+		if minor, err := strconv.Atoi(strings.TrimPrefix(version, "1.")); err == nil && minor >= 30 {
+			amiFamily = "AmazonLinux2023"
+		}
+	}
+
+	switch amiFamily {
+	case "AmazonLinux2":
+		return lo.Ternary(arm, ekstypes.AMITypesAl2Arm64, ekstypes.AMITypesAl2X8664), nil
+	case "AmazonLinux2023":
+		return lo.Ternary(arm, ekstypes.AMITypesAl2023Arm64Standard, ekstypes.AMITypesAl2023X8664Standard), nil
+	case "Bottlerocket":
+		return lo.Ternary(arm, ekstypes.AMITypesBottlerocketArm64, ekstypes.AMITypesBottlerocketX8664), nil
+	case "WindowsServer2019FullContainer":
+		return ekstypes.AMITypesWindowsFull2019X8664, nil
+	case "WindowsServer2019CoreContainer":
+		return ekstypes.AMITypesWindowsCore2019X8664, nil
+	case "WindowsServer2022FullContainer":
+		return ekstypes.AMITypesWindowsFull2022X8664, nil
+	case "WindowsServer2022CoreContainer":
+		return ekstypes.AMITypesWindowsCore2022X8664, nil
+	}
+
+	if strings.HasPrefix(amiFamily, "Ubuntu") || amiFamily == "AmazonLinux2023Custom" {
+		warnings.Warnf(`nodegroup "%s": amiFamily "%s" is migrated as Custom, make sure "amiSelectorTerms" and "userData" bootstrap the nodes`, ng.Name, amiFamily)
+		return ekstypes.AMITypesCustom, nil
+	}
+	return "", fmt.Errorf(`nodegroup "%s" has unsupported amiFamily "%s"`, ng.Name, amiFamily)
+}
+
+// Resolves subnet IDs, names and availability zones from the VPC of ClusterConfig.
+// Subnets are selected by eksctl tags when they are not in the ClusterConfig.
+func (c *ClusterConfig) subnets(ng NodeGroup) ([]string, map[string]string, error) {
+	known := map[string]Subnet{}
+	if c.VPC != nil && c.VPC.Subnets != nil {
+		subnets := lo.Ternary(ng.PrivateNetworking, c.VPC.Subnets.Private, c.VPC.Subnets.Public)
+		if len(ng.Subnets) > 0 {
+			subnets = lo.Assign(c.VPC.Subnets.Public, c.VPC.Subnets.Private)
+		}
+		for name, subnet := range subnets {
+			known[name] = subnet
+		}
+	}
+
+	if len(ng.Subnets) == 0 {
+		ids := lo.FilterMap(lo.Values(known), func(subnet Subnet, _ int) (string, bool) {
+			return subnet.ID, subnet.ID != ""
+		})
+		sort.Strings(ids)
+		if len(ids) > 0 {
+			return ids, nil, nil
+		}
+		return nil, map[string]string{
+			ClusterNameTagKey: c.Metadata.Name,
+			lo.Ternary(ng.PrivateNetworking, PrivateSubnetTagKey, PublicSubnetTagKey): "1",
+		}, nil
+	}
+
+	ids := []string{}
+	for _, ref := range ng.Subnets {
+		if strings.HasPrefix(ref, "subnet-") {
+			ids = append(ids, ref)
+			continue
+		}
+		if subnet, ok := known[ref]; ok && subnet.ID != "" {
+			ids = append(ids, subnet.ID)
+			continue
+		}
+		byAZ := lo.Filter(lo.Values(known), func(subnet Subnet, _ int) bool {
+			return subnet.ID != "" && subnet.AZ == ref
+		})
+		if len(byAZ) == 0 {
+			return nil, nil, fmt.Errorf(`nodegroup "%s" references subnet "%s" which has no ID in the ClusterConfig`, ng.Name, ref)
+		}
+		for _, subnet := range byAZ {
+			ids = append(ids, subnet.ID)
+		}
+	}
+	sort.Strings(ids)
+	return lo.Uniq(ids), nil, nil
+}
+
+func (ng NodeGroup) taints() []ekstypes.Taint {
+	return lo.Map(ng.Taints, func(t Taint, _ int) ekstypes.Taint {
+		return ekstypes.Taint{
+			Key:    lo.ToPtr(t.Key),
+			Value:  lo.ToPtr(t.Value),
+			Effect: taintEffect(t.Effect),
+		}
+	})
+}
+
+// eksctl taints use Kubernetes effect names
+func taintEffect(effect string) ekstypes.TaintEffect {
+	switch effect {
+	case "NoExecute":
+		return ekstypes.TaintEffectNoExecute
+	case "PreferNoSchedule":
+		return ekstypes.TaintEffectPreferNoSchedule
+	default:
+		return ekstypes.TaintEffectNoSchedule
+	}
+}
+
+// Returns kubelet configuration from maxPodsPerNode and kubeletExtraConfig, settings Karpenter does not support are skipped
+func (ng NodeGroup) kubelet() (*sigkarpenter.KubeletConfiguration, error) {
+	kubelet, unsupported, err := karpenteraws.KubeletFromConfig(ng.KubeletExtraConfig)
+	if err != nil {
+		return nil, fmt.Errorf(`invalid kubeletExtraConfig for nodegroup "%s": %w`, ng.Name, err)
+	}
+	for _, key := range unsupported {
+		warnings.Warnf(`nodegroup "%s": kubeletExtraConfig "%s" is not supported by Karpenter and is not migrated`, ng.Name, key)
+	}
+
+	if ng.MaxPodsPerNode != nil {
+		kubelet = lo.Ternary(kubelet == nil, &sigkarpenter.KubeletConfiguration{}, kubelet)
+		kubelet.MaxPods = ng.MaxPodsPerNode
+	}
+	return kubelet, nil
+}
+
+// Applies volume settings to the default block device mappings of the AMI family,
+// Bottlerocket volume settings apply to the data volume
+func (ng NodeGroup) blockDeviceMappings(amiType ekstypes.AMITypes) []ec2types.LaunchTemplateBlockDeviceMapping {
+	family := (&karpenteraws.NodeGroup{Nodegroup: &ekstypes.Nodegroup{AmiType: amiType}}).AMIFamily()
+	if *family == awskarpenter.AMIFamilyCustom {
+		family = lo.ToPtr(awskarpenter.AMIFamilyUbuntu)
+	}
+	defaults := awskarpenterprovider.GetAMIFamily(family, &awskarpenterprovider.Options{}).DefaultBlockDeviceMappings()
+
+	mappings := []ec2types.LaunchTemplateBlockDeviceMapping{}
+	for idx, mapping := range defaults {
+		ebs := &ec2types.LaunchTemplateEbsBlockDevice{
+			VolumeSize:          lo.ToPtr(int32(mapping.EBS.VolumeSize.Value() / karpenteraws.GiB)),
+			VolumeType:          ec2types.VolumeType(lo.FromPtr(mapping.EBS.VolumeType)),
+			Encrypted:           mapping.EBS.Encrypted,
+			DeleteOnTermination: lo.ToPtr(true),
+		}
+		if idx == len(defaults)-1 {
+			ebs.VolumeSize = lo.ToPtr(lo.FromPtrOr(ng.VolumeSize, DefaultVolumeSize))
+			ebs.VolumeType = ec2types.VolumeType(lo.Ternary(ng.VolumeType != "", ng.VolumeType, DefaultVolumeType))
+			ebs.Iops = ng.VolumeIOPS
+			ebs.Throughput = ng.VolumeThroughput
+			ebs.KmsKeyId = lo.EmptyableToPtr(ng.VolumeKmsKeyID)
+			if ng.VolumeEncrypted != nil {
+				ebs.Encrypted = ng.VolumeEncrypted
+			}
+		}
+		mappings = append(mappings, ec2types.LaunchTemplateBlockDeviceMapping{
+			DeviceName: mapping.DeviceName,
+			Ebs:        ebs,
+		})
+	}
+	return mappings
+}
+
+// eksctl requires IMDSv2 unless disableIMDSv1 is set to false
+func (ng NodeGroup) metadataOptions() *ec2types.LaunchTemplateInstanceMetadataOptions {
+	tokens := ec2types.LaunchTemplateHttpTokensStateRequired
+	if ng.DisableIMDSv1 != nil && !*ng.DisableIMDSv1 {
+		tokens = ec2types.LaunchTemplateHttpTokensStateOptional
+	}
+	return &ec2types.LaunchTemplateInstanceMetadataOptions{
+		HttpEndpoint:            ec2types.LaunchTemplateInstanceMetadataEndpointStateEnabled,
+		HttpPutResponseHopLimit: lo.ToPtr(int32(2)),
+		HttpTokens:              tokens,
+	}
+}
+
+// Returns base64 encoded user data running preBootstrapCommands before Karpenter bootstraps the node,
+// Bottlerocket user data holds bottlerocket.settings as TOML
+func (ng NodeGroup) userData(amiType ekstypes.AMITypes) (*string, error) {
+	var userData string
+	switch {
+	case strings.HasPrefix(string(amiType), "BOTTLEROCKET"):
+		if len(ng.PreBootstrapCommands) > 0 {
+			warnings.Warnf(`nodegroup "%s": preBootstrapCommands are not supported by Bottlerocket and are not migrated, use bootstrap containers`, ng.Name)
+		}
+		if ng.Bottlerocket == nil || len(ng.Bottlerocket.Settings) == 0 {
+			return nil, nil
+		}
+		data, err := toml.Marshal(map[string]any{"settings": wholeNumbers(ng.Bottlerocket.Settings)})
+		if err != nil {
+			return nil, fmt.Errorf(`invalid bottlerocket.settings for nodegroup "%s": %w`, ng.Name, err)
+		}
+		userData = string(data)
+	case len(ng.PreBootstrapCommands) == 0:
+		return nil, nil
+	case strings.HasPrefix(string(amiType), "WINDOWS"):
+		userData = strings.Join(ng.PreBootstrapCommands, "\n")
+	default:
+		userData = karpenteraws.MIMEUserData(userDataBoundary, []karpenteraws.MIMEPart{{
+			ContentType: karpenteraws.ShellScriptMediaType + `; charset="us-ascii"`,
+			Body:        "#!/bin/bash\n" + strings.Join(ng.PreBootstrapCommands, "\n"),
+		}})
+	}
+	return lo.ToPtr(base64.StdEncoding.EncodeToString([]byte(userData))), nil
+}
+
+// YAML numbers are decoded as float64, whole numbers are converted to int64 so they are written as TOML integers
+func wholeNumbers(val any) any {
+	switch typed := val.(type) {
+	case map[string]any:
+		converted := map[string]any{}
+		for key, v := range typed {
+			converted[key] = wholeNumbers(v)
+		}
+		return converted
+	case []any:
+		return lo.Map(typed, func(v any, _ int) any { return wholeNumbers(v) })
+	case float64:
+		if typed == float64(int64(typed)) {
+			return int64(typed)
+		}
+	}
+	return val
+}
+
+// Warns about settings which have no Karpenter equivalent
+func (ng NodeGroup) warnUnsupported() {
+	if ng.OverrideBootstrapCommand != "" {
+		warnings.Warnf(bootstrapCommandWarning, ng.Name)
+	}
+	if ng.IAM != nil && len(ng.IAM.WithAddonPolicies) > 0 {
+		policies := lo.Keys(lo.PickBy(ng.IAM.WithAddonPolicies, func(_ string, enabled bool) bool { return enabled }))
+		sort.Strings(policies)
+		if len(policies) > 0 {
+			warnings.Warnf(`nodegroup "%s": iam.withAddonPolicies (%s) are not migrated, attach the policies to the node role`, ng.Name, strings.Join(policies, ","))
+		}
+	}
+	if lo.FromPtr(ng.PropagateASGTags) {
+		warnings.Warnf(`nodegroup "%s": propagateASGTags is not needed with Karpenter, labels and taints are set on the NodePool`, ng.Name)
+	}
+}
+
+func int32Ptr(i *int) *int32 {
+	if i == nil {
+		return nil
+	}
+	return lo.ToPtr(int32(*i))
+}
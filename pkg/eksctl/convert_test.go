@@ -0,0 +1,189 @@
+package eksctl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/punkwalker/karpenter-generate/pkg/karpenteraws"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+func TestNodeGroups(t *testing.T) {
+	cfg, err := Load("testdata/cluster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeGroups, err := NodeGroups(cfg, "eks-node-role")
+	if err != nil {
+		t.Fatalf("NodeGroups() error = %v", err)
+	}
+	byName := lo.KeyBy(nodeGroups, func(ng *karpenteraws.NodeGroup) string { return ng.Name() })
+
+	tests := []struct {
+		name          string
+		nodegroup     string
+		amiType       ekstypes.AMITypes
+		capacityTypes []string
+		subnets       []string
+		role          string
+		budgetNodes   []string
+	}{
+		{
+			name:          "Self-managed nodegroup with instances distribution",
+			nodegroup:     "ng-selfmanaged",
+			amiType:       ekstypes.AMITypesAl2X8664,
+			capacityTypes: []string{"on-demand", "spot"},
+			subnets:       []string{"subnet-0aaaaaaaaaaaaaaaa", "subnet-0bbbbbbbbbbbbbbbb"},
+			role:          "eks-node-role",
+		},
+		{
+			name:          "Managed Spot nodegroup with Graviton instance types",
+			nodegroup:     "mng-arm",
+			amiType:       ekstypes.AMITypesAl2023Arm64Standard,
+			capacityTypes: []string{"spot"},
+			subnets:       []string{"subnet-0aaaaaaaaaaaaaaaa"},
+			role:          "eks-node-role",
+			budgetNodes:   []string{"33%"},
+		},
+		{
+			name:          "Managed nodegroup with instance selector and instance profile",
+			nodegroup:     "mng-selector",
+			amiType:       ekstypes.AMITypesBottlerocketX8664,
+			capacityTypes: []string{"on-demand"},
+			subnets:       []string{"subnet-0aaaaaaaaaaaaaaaa", "subnet-0bbbbbbbbbbbbbbbb"},
+			role:          "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng, ok := byName[tt.nodegroup]
+			if !ok {
+				t.Fatalf("nodegroup %s not found", tt.nodegroup)
+			}
+			if ng.AmiType != tt.amiType {
+				t.Errorf("AmiType = %v, expected %v", ng.AmiType, tt.amiType)
+			}
+			if got := ng.CapacityTypes(); !reflect.DeepEqual(got, tt.capacityTypes) {
+				t.Errorf("CapacityTypes() = %v, expected %v", got, tt.capacityTypes)
+			}
+			if !reflect.DeepEqual(ng.Subnets, tt.subnets) {
+				t.Errorf("Subnets = %v, expected %v", ng.Subnets, tt.subnets)
+			}
+			if got := ng.Role(); got != tt.role {
+				t.Errorf("Role() = %v, expected %v", got, tt.role)
+			}
+			budgetNodes := lo.Map(ng.DefaultBudgets(), func(b sigkarpenter.Budget, _ int) string { return b.Nodes })
+			if strings.Join(budgetNodes, ",") != strings.Join(tt.budgetNodes, ",") {
+				t.Errorf("DefaultBudgets() nodes = %v, expected %v", budgetNodes, tt.budgetNodes)
+			}
+		})
+	}
+
+	for _, apiVersion := range []string{options.APIVersionV1beta1, options.APIVersionV1} {
+		objs, err := karpenteraws.GenerateFromNodeGroups(&options.Options{APIVersion: apiVersion}, nodeGroups)
+		if err != nil {
+			t.Fatalf("GenerateFromNodeGroups() %s error = %v", apiVersion, err)
+		}
+		if len(objs) != 6 {
+			t.Errorf("GenerateFromNodeGroups() %s returned %d objects, expected 6", apiVersion, len(objs))
+		}
+	}
+}
+
+func TestNodeGroups_MissingRole(t *testing.T) {
+	cfg, err := Load("testdata/cluster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NodeGroups(cfg, ""); err == nil {
+		t.Errorf("NodeGroups() expected error for nodegroup without role")
+	}
+}
+
+func TestNodeGroup_Kubelet(t *testing.T) {
+	ng := NodeGroup{
+		Name:           "ng",
+		MaxPodsPerNode: lo.ToPtr(int32(58)),
+		KubeletExtraConfig: map[string]any{
+			"systemReserved": map[string]any{"cpu": "100m"},
+			"featureGates":   map[string]any{"RotateKubeletServerCertificate": true},
+		},
+	}
+
+	got, err := ng.kubelet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo.FromPtr(got.MaxPods) != 58 || !reflect.DeepEqual(got.SystemReserved, map[string]string{"cpu": "100m"}) {
+		t.Errorf("NodeGroup.kubelet() = %+v", got)
+	}
+
+	got, err = NodeGroup{Name: "ng"}.kubelet()
+	if err != nil || got != nil {
+		t.Errorf("NodeGroup.kubelet() = %+v, %v, expected nil", got, err)
+	}
+}
+
+func TestTaints_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected Taints
+	}{
+		{
+			name: "List format",
+			data: "- key: dedicated\n  value: data\n  effect: NoExecute\n",
+			expected: Taints{
+				{Key: "dedicated", Value: "data", Effect: "NoExecute"},
+			},
+		},
+		{
+			name: "Map format",
+			data: "dedicated: data:NoSchedule\nspecial: ':PreferNoSchedule'\n",
+			expected: Taints{
+				{Key: "dedicated", Value: "data", Effect: "NoSchedule"},
+				{Key: "special", Value: "", Effect: "PreferNoSchedule"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Taints{}
+			if err := yaml.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Taints.UnmarshalJSON() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMemoryMiB(t *testing.T) {
+	tests := []struct {
+		memory   string
+		expected int64
+	}{
+		{memory: "16GiB", expected: 16384},
+		{memory: "16", expected: 16384},
+		{memory: "512Mi", expected: 512},
+		{memory: "", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.memory, func(t *testing.T) {
+			if got := memoryMiB(tt.memory); got != tt.expected {
+				t.Errorf("memoryMiB() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
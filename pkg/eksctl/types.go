@@ -0,0 +1,156 @@
+package eksctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/yaml"
+)
+
+// ClusterConfig is the subset of the eksctl.io/v1alpha5 ClusterConfig which describes nodegroups
+type ClusterConfig struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name    string `json:"name"`
+		Region  string `json:"region"`
+		Version string `json:"version"`
+	} `json:"metadata"`
+	VPC *struct {
+		Subnets *struct {
+			Private map[string]Subnet `json:"private"`
+			Public  map[string]Subnet `json:"public"`
+		} `json:"subnets"`
+	} `json:"vpc"`
+	NodeGroups        []NodeGroup `json:"nodeGroups"`
+	ManagedNodeGroups []NodeGroup `json:"managedNodeGroups"`
+}
+
+type Subnet struct {
+	ID string `json:"id"`
+	AZ string `json:"az"`
+}
+
+// NodeGroup holds the fields shared by eksctl nodeGroups and managedNodeGroups
+type NodeGroup struct {
+	Name                     string              `json:"name"`
+	AMIFamily                string              `json:"amiFamily"`
+	AMI                      string              `json:"ami"`
+	InstanceType             string              `json:"instanceType"`
+	InstanceTypes            []string            `json:"instanceTypes"`
+	InstanceSelector         *InstanceSelector   `json:"instanceSelector"`
+	InstancesDistribution    *InstancesDistrib   `json:"instancesDistribution"`
+	Spot                     bool                `json:"spot"`
+	MinSize                  *int                `json:"minSize"`
+	MaxSize                  *int                `json:"maxSize"`
+	DesiredCapacity          *int                `json:"desiredCapacity"`
+	UpdateConfig             *UpdateConfig       `json:"updateConfig"`
+	VolumeSize               *int32              `json:"volumeSize"`
+	VolumeType               string              `json:"volumeType"`
+	VolumeIOPS               *int32              `json:"volumeIOPS"`
+	VolumeThroughput         *int32              `json:"volumeThroughput"`
+	VolumeEncrypted          *bool               `json:"volumeEncrypted"`
+	VolumeKmsKeyID           string              `json:"volumeKmsKeyID"`
+	Labels                   map[string]string   `json:"labels"`
+	Taints                   Taints              `json:"taints"`
+	Tags                     map[string]string   `json:"tags"`
+	Subnets                  []string            `json:"subnets"`
+	PrivateNetworking        bool                `json:"privateNetworking"`
+	SecurityGroups           *SecurityGroups     `json:"securityGroups"`
+	IAM                      *IAM                `json:"iam"`
+	MaxPodsPerNode           *int32              `json:"maxPodsPerNode"`
+	KubeletExtraConfig       map[string]any      `json:"kubeletExtraConfig"`
+	PreBootstrapCommands     []string            `json:"preBootstrapCommands"`
+	OverrideBootstrapCommand string              `json:"overrideBootstrapCommand"`
+	PropagateASGTags         *bool               `json:"propagateASGTags"`
+	DisableIMDSv1            *bool               `json:"disableIMDSv1"`
+	Bottlerocket             *BottlerocketConfig `json:"bottlerocket"`
+}
+
+// UpdateConfig is the update strategy of managedNodeGroups
+type UpdateConfig struct {
+	MaxUnavailable           *int `json:"maxUnavailable"`
+	MaxUnavailablePercentage *int `json:"maxUnavailablePercentage"`
+}
+
+type InstanceSelector struct {
+	VCPUs           int    `json:"vCPUs"`
+	Memory          string `json:"memory"`
+	GPUs            *int   `json:"gpus"`
+	CPUArchitecture string `json:"cpuArchitecture"`
+}
+
+type InstancesDistrib struct {
+	InstanceTypes                       []string `json:"instanceTypes"`
+	OnDemandBaseCapacity                *int     `json:"onDemandBaseCapacity"`
+	OnDemandPercentageAboveBaseCapacity *int     `json:"onDemandPercentageAboveBaseCapacity"`
+}
+
+type SecurityGroups struct {
+	AttachIDs []string `json:"attachIDs"`
+}
+
+type IAM struct {
+	InstanceProfileARN string          `json:"instanceProfileARN"`
+	InstanceRoleARN    string          `json:"instanceRoleARN"`
+	WithAddonPolicies  map[string]bool `json:"withAddonPolicies"`
+}
+
+type BottlerocketConfig struct {
+	Settings map[string]any `json:"settings"`
+}
+
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"`
+}
+
+// Taints accepts the list format and the deprecated map format ("key": "value:Effect")
+type Taints []Taint
+
+func (t *Taints) UnmarshalJSON(data []byte) error {
+	list := []Taint{}
+	if err := json.Unmarshal(data, &list); err == nil {
+		*t = list
+		return nil
+	}
+
+	taintMap := map[string]string{}
+	if err := json.Unmarshal(data, &taintMap); err != nil {
+		return err
+	}
+	keys := lo.Keys(taintMap)
+	sort.Strings(keys)
+	for _, key := range keys {
+		taint := Taint{Key: key, Value: taintMap[key]}
+		if idx := strings.LastIndex(taint.Value, ":"); idx >= 0 {
+			taint.Value, taint.Effect = taint.Value[:idx], taint.Value[idx+1:]
+		}
+		*t = append(*t, taint)
+	}
+	return nil
+}
+
+// Load reads an eksctl ClusterConfig YAML file
+func Load(file string) (*ClusterConfig, error) {
+	data, err := os.ReadFile(file) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &ClusterConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf(`failed to parse "%s": %w`, file, err)
+	}
+	if cfg.Kind != "ClusterConfig" {
+		return nil, fmt.Errorf(`"%s" is not an eksctl ClusterConfig`, file)
+	}
+	if len(cfg.NodeGroups)+len(cfg.ManagedNodeGroups) == 0 {
+		return nil, fmt.Errorf(`no nodeGroups or managedNodeGroups found in "%s"`, file)
+	}
+	return cfg, nil
+}
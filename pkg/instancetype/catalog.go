@@ -0,0 +1,112 @@
+package instancetype
+
+// family lists the sizes of an instance family whose memory is proportional to its vCPUs
+type family struct {
+	architecture     string
+	memoryMiBPerVCPU int64
+	sizes            []string
+	// vCPUs of the metal size, metal sizes share vCPUs of the largest virtualized size
+	metalVCPU int64
+}
+
+var (
+	sizes5Intel = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "metal"}
+	sizes5AMD   = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge"}
+	sizesC5     = []string{"large", "xlarge", "2xlarge", "4xlarge", "9xlarge", "12xlarge", "18xlarge", "24xlarge", "metal"}
+	sizes6Intel = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "32xlarge", "metal"}
+	sizes6AMD   = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "32xlarge", "48xlarge", "metal"}
+	sizes7Intel = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "48xlarge"}
+	sizes7AMD   = []string{"medium", "large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "32xlarge", "48xlarge"}
+	sizesFlex   = []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge"}
+	sizesG      = []string{"medium", "large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "metal"}
+	sizesGN     = []string{"medium", "large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge"}
+	sizes8G     = []string{"medium", "large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "48xlarge"}
+	sizesG4dn   = []string{"xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "metal"}
+	sizesG5     = []string{"xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge", "24xlarge", "48xlarge"}
+)
+
+// Bundled catalog of current generation families, other instance types are described with the EC2 API
+var catalog = map[string]family{
+	// General purpose
+	"m5":       {ArchitectureAmd64, 4096, sizes5Intel, 96},
+	"m5d":      {ArchitectureAmd64, 4096, sizes5Intel, 96},
+	"m5n":      {ArchitectureAmd64, 4096, sizes5Intel, 96},
+	"m5dn":     {ArchitectureAmd64, 4096, sizes5Intel, 96},
+	"m5a":      {ArchitectureAmd64, 4096, sizes5AMD, 0},
+	"m5ad":     {ArchitectureAmd64, 4096, sizes5AMD, 0},
+	"m6i":      {ArchitectureAmd64, 4096, sizes6Intel, 128},
+	"m6id":     {ArchitectureAmd64, 4096, sizes6Intel, 128},
+	"m6in":     {ArchitectureAmd64, 4096, sizes6Intel, 128},
+	"m6idn":    {ArchitectureAmd64, 4096, sizes6Intel, 128},
+	"m6a":      {ArchitectureAmd64, 4096, sizes6AMD, 192},
+	"m7i":      {ArchitectureAmd64, 4096, sizes7Intel, 0},
+	"m7i-flex": {ArchitectureAmd64, 4096, sizesFlex, 0},
+	"m7a":      {ArchitectureAmd64, 4096, sizes7AMD, 0},
+	"m6g":      {ArchitectureArm64, 4096, sizesG, 64},
+	"m6gd":     {ArchitectureArm64, 4096, sizesG, 64},
+	"m7g":      {ArchitectureArm64, 4096, sizesG, 64},
+	"m7gd":     {ArchitectureArm64, 4096, sizesG, 64},
+	"m8g":      {ArchitectureArm64, 4096, sizes8G, 0},
+	// Compute optimized
+	"c5":   {ArchitectureAmd64, 2048, sizesC5, 96},
+	"c5d":  {ArchitectureAmd64, 2048, sizesC5, 96},
+	"c5a":  {ArchitectureAmd64, 2048, sizes5AMD, 0},
+	"c5ad": {ArchitectureAmd64, 2048, sizes5AMD, 0},
+	"c6i":  {ArchitectureAmd64, 2048, sizes6Intel, 128},
+	"c6id": {ArchitectureAmd64, 2048, sizes6Intel, 128},
+	"c6in": {ArchitectureAmd64, 2048, sizes6Intel, 128},
+	"c6a":  {ArchitectureAmd64, 2048, sizes6AMD, 192},
+	"c7i":  {ArchitectureAmd64, 2048, sizes7Intel, 0},
+	"c7a":  {ArchitectureAmd64, 2048, sizes7AMD, 0},
+	"c6g":  {ArchitectureArm64, 2048, sizesG, 64},
+	"c6gd": {ArchitectureArm64, 2048, sizesG, 64},
+	"c6gn": {ArchitectureArm64, 2048, sizesGN, 0},
+	"c7g":  {ArchitectureArm64, 2048, sizesG, 64},
+	"c7gd": {ArchitectureArm64, 2048, sizesG, 64},
+	"c7gn": {ArchitectureArm64, 2048, sizesG, 64},
+	"c8g":  {ArchitectureArm64, 2048, sizes8G, 0},
+	// Memory optimized
+	"r5":   {ArchitectureAmd64, 8192, sizes5Intel, 96},
+	"r5d":  {ArchitectureAmd64, 8192, sizes5Intel, 96},
+	"r5n":  {ArchitectureAmd64, 8192, sizes5Intel, 96},
+	"r5dn": {ArchitectureAmd64, 8192, sizes5Intel, 96},
+	"r5a":  {ArchitectureAmd64, 8192, sizes5AMD, 0},
+	"r5ad": {ArchitectureAmd64, 8192, sizes5AMD, 0},
+	"r6i":  {ArchitectureAmd64, 8192, sizes6Intel, 128},
+	"r6id": {ArchitectureAmd64, 8192, sizes6Intel, 128},
+	"r6a":  {ArchitectureAmd64, 8192, sizes6AMD, 192},
+	"r7i":  {ArchitectureAmd64, 8192, sizes7Intel, 0},
+	"r7a":  {ArchitectureAmd64, 8192, sizes7AMD, 0},
+	"r6g":  {ArchitectureArm64, 8192, sizesG, 64},
+	"r6gd": {ArchitectureArm64, 8192, sizesG, 64},
+	"r7g":  {ArchitectureArm64, 8192, sizesG, 64},
+	"r7gd": {ArchitectureArm64, 8192, sizesG, 64},
+	"r8g":  {ArchitectureArm64, 8192, sizes8G, 0},
+	// Accelerated computing, GPUs are not part of the capacity
+	"g4dn": {ArchitectureAmd64, 4096, sizesG4dn, 96},
+	"g5":   {ArchitectureAmd64, 4096, sizesG5, 0},
+}
+
+// Burstable instance types do not have memory proportional to their vCPUs
+var burstable = map[string]Info{}
+
+func init() {
+	sizes := []struct {
+		size      string
+		vcpu      int64
+		memoryMiB int64
+	}{
+		{"nano", 2, 512},
+		{"micro", 2, 1024},
+		{"small", 2, 2048},
+		{"medium", 2, 4096},
+		{"large", 2, 8192},
+		{"xlarge", 4, 16384},
+		{"2xlarge", 8, 32768},
+	}
+	for f, arch := range map[string]string{"t3": ArchitectureAmd64, "t3a": ArchitectureAmd64, "t4g": ArchitectureArm64} {
+		for _, s := range sizes {
+			burstable[f+"."+s.size] = Info{Architecture: arch, VCPU: s.vcpu, MemoryMiB: s.memoryMiB}
+		}
+	}
+}
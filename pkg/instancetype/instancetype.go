@@ -0,0 +1,127 @@
+package instancetype
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+)
+
+const (
+	ArchitectureAmd64 = "amd64"
+	ArchitectureArm64 = "arm64"
+)
+
+// Category, generation and attributes of the family, e.g.: "c", "6" and "gn" of c6gn
+var familyPattern = regexp.MustCompile(`^([a-z]+)([0-9]+)([a-z-]*)$`)
+
+// Info is the capacity of an instance type
+type Info struct {
+	Name         string
+	Architecture string
+	VCPU         int64
+	MemoryMiB    int64
+}
+
+// Family returns the family of the instance type, e.g.: m5 of m5.large
+func (i Info) Family() string {
+	family, _, _ := strings.Cut(i.Name, ".")
+	return family
+}
+
+// Category returns the instance category, e.g.: m of m5.large
+func (i Info) Category() string {
+	if match := familyPattern.FindStringSubmatch(i.Family()); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// Generation returns the instance generation, e.g.: 5 of m5.large, 0 when it can not be parsed
+func (i Info) Generation() int {
+	if match := familyPattern.FindStringSubmatch(i.Family()); match != nil {
+		generation, _ := strconv.Atoi(match[2])
+		return generation
+	}
+	return 0
+}
+
+// Lookup returns the instance type from the bundled catalog
+func Lookup(name string) (Info, bool) {
+	family, size, _ := strings.Cut(name, ".")
+	if info, ok := burstable[name]; ok {
+		info.Name = name
+		return info, true
+	}
+	f, ok := catalog[family]
+	if !ok || !lo.Contains(f.sizes, size) {
+		return Info{}, false
+	}
+	vcpu := sizeVCPU(size)
+	if size == "metal" {
+		vcpu = f.metalVCPU
+	}
+	if vcpu == 0 {
+		return Info{}, false
+	}
+	return Info{
+		Name:         name,
+		Architecture: f.architecture,
+		VCPU:         vcpu,
+		MemoryMiB:    vcpu * f.memoryMiBPerVCPU,
+	}, true
+}
+
+// All returns the instance types of the bundled catalog sorted by name
+func All() []Info {
+	names := lo.Keys(burstable)
+	for name, f := range catalog {
+		names = append(names, lo.Map(f.sizes, func(size string, _ int) string { return name + "." + size })...)
+	}
+	sort.Strings(names)
+	return lo.FilterMap(names, func(name string, _ int) (Info, bool) {
+		return Lookup(name)
+	})
+}
+
+// FromEC2 returns the instance type of "ec2:DescribeInstanceTypes" output
+func FromEC2(info ec2types.InstanceTypeInfo) Info {
+	i := Info{Name: string(info.InstanceType)}
+	if info.VCpuInfo != nil {
+		i.VCPU = int64(lo.FromPtr(info.VCpuInfo.DefaultVCpus))
+	}
+	if info.MemoryInfo != nil {
+		i.MemoryMiB = lo.FromPtr(info.MemoryInfo.SizeInMiB)
+	}
+	if info.ProcessorInfo != nil {
+		switch {
+		case lo.Contains(info.ProcessorInfo.SupportedArchitectures, ec2types.ArchitectureTypeX8664):
+			i.Architecture = ArchitectureAmd64
+		case lo.Contains(info.ProcessorInfo.SupportedArchitectures, ec2types.ArchitectureTypeArm64):
+			i.Architecture = ArchitectureArm64
+		}
+	}
+	return i
+}
+
+// Returns vCPUs of non burstable sizes, 0 for sizes whose vCPUs depend on the family
+func sizeVCPU(size string) int64 {
+	switch size {
+	case "medium":
+		return 1
+	case "large":
+		return 2
+	case "xlarge":
+		return 4
+	}
+	if multiplier, ok := strings.CutSuffix(size, "xlarge"); ok {
+		n, err := strconv.ParseInt(multiplier, 10, 64)
+		if err == nil {
+			return n * 4
+		}
+	}
+	return 0
+}
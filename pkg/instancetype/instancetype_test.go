@@ -0,0 +1,71 @@
+package instancetype
+
+import (
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Info
+		found    bool
+	}{
+		{name: "m5.large", expected: Info{Name: "m5.large", Architecture: ArchitectureAmd64, VCPU: 2, MemoryMiB: 8192}, found: true},
+		{name: "c5.9xlarge", expected: Info{Name: "c5.9xlarge", Architecture: ArchitectureAmd64, VCPU: 36, MemoryMiB: 73728}, found: true},
+		{name: "r6g.medium", expected: Info{Name: "r6g.medium", Architecture: ArchitectureArm64, VCPU: 1, MemoryMiB: 8192}, found: true},
+		{name: "m6i.metal", expected: Info{Name: "m6i.metal", Architecture: ArchitectureAmd64, VCPU: 128, MemoryMiB: 524288}, found: true},
+		{name: "t3.micro", expected: Info{Name: "t3.micro", Architecture: ArchitectureAmd64, VCPU: 2, MemoryMiB: 1024}, found: true},
+		{name: "m5a.metal", found: false},
+		{name: "x2idn.16xlarge", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := Lookup(tt.name)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestInfo_CategoryGeneration(t *testing.T) {
+	tests := []struct {
+		name       string
+		category   string
+		generation int
+	}{
+		{name: "m5.large", category: "m", generation: 5},
+		{name: "c6gn.medium", category: "c", generation: 6},
+		{name: "m7i-flex.large", category: "m", generation: 7},
+		{name: "u-6tb1.metal", category: "", generation: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := Info{Name: tt.name}
+			assert.Equal(t, tt.category, info.Category())
+			assert.Equal(t, tt.generation, info.Generation())
+		})
+	}
+}
+
+func TestFromEC2(t *testing.T) {
+	got := FromEC2(ec2types.InstanceTypeInfo{
+		InstanceType:  "x2gd.large",
+		VCpuInfo:      &ec2types.VCpuInfo{DefaultVCpus: lo.ToPtr(int32(2))},
+		MemoryInfo:    &ec2types.MemoryInfo{SizeInMiB: lo.ToPtr(int64(32768))},
+		ProcessorInfo: &ec2types.ProcessorInfo{SupportedArchitectures: []ec2types.ArchitectureType{ec2types.ArchitectureTypeArm64}},
+	})
+	assert.Equal(t, Info{Name: "x2gd.large", Architecture: ArchitectureArm64, VCPU: 2, MemoryMiB: 32768}, got)
+}
+
+func TestAll(t *testing.T) {
+	all := All()
+	assert.IsIncreasing(t, lo.Map(all, func(info Info, _ int) string { return info.Name }))
+	assert.Contains(t, all, Info{Name: "t4g.micro", Architecture: ArchitectureArm64, VCPU: 2, MemoryMiB: 1024})
+	assert.Contains(t, all, Info{Name: "c5.9xlarge", Architecture: ArchitectureAmd64, VCPU: 36, MemoryMiB: 73728})
+}
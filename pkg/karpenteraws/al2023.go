@@ -0,0 +1,104 @@
+package karpenteraws
+
+import (
+	"sort"
+	"strings"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+const (
+	NodeConfigAPIVersion    string = "node.eks.aws/v1alpha1"
+	DefaultUserDataBoundary string = "//"
+)
+
+// nodeadm local storage strategies of bootstrap.sh --local-disks values
+var localStorageStrategies = map[string]string{
+	"raid0": "RAID0",
+	"mount": "Mount",
+}
+
+// Moves an AL2 nodegroup to AL2023, bootstrap.sh arguments and kubelet flags Karpenter does not manage
+// are rewritten into a nodeadm NodeConfig and custom scripts are kept as MIME multipart user data
+func (n *NodeGroup) convertToAL2023() error {
+	if *n.AMIFamily() != awskarpenter.AMIFamilyAL2 {
+		warnings.Warnf(`nodegroup "%s": AMI family "%s" can not be converted to AL2023`, n.Name(), *n.AMIFamily())
+		return nil
+	}
+	if n.AmiID() != "" {
+		warnings.Warnf(`nodegroup "%s": AL2 AMI "%s" is replaced by the latest AL2023 EKS optimized AMI`, n.Name(), n.AmiID())
+	}
+	n.TargetAMIFamily = awskarpenter.AMIFamilyAL2023
+
+	settings := n.UserDataSettings
+	if settings == nil {
+		return nil
+	}
+
+	parts := []MIMEPart{}
+	boundary := DefaultUserDataBoundary
+	if nodeConfig, err := settings.nodeConfig(n.Name()); err != nil {
+		return err
+	} else if nodeConfig != "" {
+		parts = append(parts, MIMEPart{ContentType: NodeConfigMediaType, Body: nodeConfig})
+	}
+
+	// AL2023 runs shell scripts only from MIME multipart user data
+	switch {
+	case isMIME(settings.UserData):
+		mimeBoundary, mimeParts, err := splitMIME(settings.UserData)
+		if err != nil {
+			return err
+		}
+		boundary = mimeBoundary
+		parts = append(parts, mimeParts...)
+	case settings.UserData != "":
+		parts = append(parts, MIMEPart{ContentType: ShellScriptMediaType + `; charset="us-ascii"`, Body: settings.UserData})
+	}
+
+	settings.UserData = ""
+	if len(parts) > 0 {
+		settings.UserData = MIMEUserData(boundary, parts)
+	}
+	settings.KubeletFlags = nil
+	settings.BootstrapArgs = nil
+	return nil
+}
+
+// Returns a NodeConfig with kubelet flags and local disks of bootstrap.sh, arguments without a nodeadm equivalent are dropped
+func (s *UserDataSettings) nodeConfig(name string) (string, error) {
+	spec := map[string]any{}
+	if len(s.KubeletFlags) > 0 {
+		spec["kubelet"] = map[string]any{"flags": s.KubeletFlags}
+	}
+
+	args := make([]string, 0, len(s.BootstrapArgs))
+	for arg := range s.BootstrapArgs {
+		args = append(args, arg)
+	}
+	sort.Strings(args)
+	for _, arg := range args {
+		val := s.BootstrapArgs[arg]
+		if strategy, ok := localStorageStrategies[strings.ToLower(val)]; ok && arg == "local-disks" {
+			spec["instance"] = map[string]any{"localStorage": map[string]any{"strategy": strategy}}
+			continue
+		}
+		warnings.Warnf(`nodegroup "%s": bootstrap.sh argument "--%s %s" has no nodeadm equivalent and is not migrated`, name, arg, val)
+	}
+
+	if len(spec) == 0 {
+		return "", nil
+	}
+	data, err := yaml.Marshal(map[string]any{
+		"apiVersion": NodeConfigAPIVersion,
+		"kind":       NodeConfigKind,
+		"spec":       spec,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,92 @@
+package karpenteraws
+
+import (
+	"encoding/base64"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+)
+
+func TestNodeGroup_MigrateUserData_AL2023(t *testing.T) {
+	userData := `#!/bin/bash
+echo "vm.max_map_count=262144" >> /etc/sysctl.conf
+/etc/eks/bootstrap.sh my-cluster --local-disks raid0 --enable-docker-bridge true --kubelet-extra-args '--max-pods=29 --v=2'
+`
+	tests := []struct {
+		name      string
+		n         NodeGroup
+		amiFamily string
+		amiTerms  int
+		userData  *string
+	}{
+		{
+			name: "Custom AMI bootstrapped with bootstrap.sh",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{NodegroupName: lo.ToPtr("ng"), AmiType: ekstypes.AMITypesCustom},
+				CustomLT: &ec2types.ResponseLaunchTemplateData{
+					ImageId:  lo.ToPtr("ami-0123456789abcdef0"),
+					UserData: lo.ToPtr(base64.StdEncoding.EncodeToString([]byte(userData))),
+				},
+			},
+			amiFamily: "AL2023",
+			amiTerms:  0,
+			userData: lo.ToPtr(`MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="//"
+
+--//
+Content-Type: application/node.eks.aws
+
+apiVersion: node.eks.aws/v1alpha1
+kind: NodeConfig
+spec:
+  instance:
+    localStorage:
+      strategy: RAID0
+  kubelet:
+    flags:
+    - --v=2
+
+--//
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+echo "vm.max_map_count=262144" >> /etc/sysctl.conf
+
+--//--
+`),
+		},
+		{
+			name: "Managed nodegroup without launch template",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{NodegroupName: lo.ToPtr("ng"), AmiType: ekstypes.AMITypesAl2X8664},
+			},
+			amiFamily: "AL2023",
+		},
+		{
+			name: "Bottlerocket is not converted",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{NodegroupName: lo.ToPtr("ng"), AmiType: ekstypes.AMITypesBottlerocketX8664},
+			},
+			amiFamily: "Bottlerocket",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.n.MigrateUserData("AL2023"); err != nil {
+				t.Fatalf("NodeGroup.MigrateUserData() error = %v", err)
+			}
+			if got := *tt.n.AMIFamily(); got != tt.amiFamily {
+				t.Errorf("NodeGroup.AMIFamily() = %v, expected %v", got, tt.amiFamily)
+			}
+			if got := len(tt.n.AMISelectorTerms()); got != tt.amiTerms {
+				t.Errorf("NodeGroup.AMISelectorTerms() returned %d terms, expected %d", got, tt.amiTerms)
+			}
+			if got := tt.n.UserData(); lo.FromPtr(got) != lo.FromPtr(tt.userData) {
+				t.Errorf("NodeGroup.UserData() = %v, expected %v", lo.FromPtr(got), lo.FromPtr(tt.userData))
+			}
+		})
+	}
+}
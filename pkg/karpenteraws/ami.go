@@ -0,0 +1,194 @@
+package karpenteraws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/instancetype"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+// ParameterGetter gets SSM parameters, GetParameter returns an empty value when the parameter is not found
+type ParameterGetter interface {
+	GetParameter(ctx context.Context, name string) (string, error)
+}
+
+// Architectures of EKS optimized AMI SSM parameters
+var ssmArchitectures = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "arm64",
+}
+
+// PinAMI sets PinnedAMIs to the AMIs of the release version of the managed nodegroup for every architecture of the NodePool,
+// AMIs are looked up in EKS optimized AMI SSM parameters, falling back to the image of the launch template EKS generated
+func (n *NodeGroup) PinAMI(ctx context.Context, ssmClient ParameterGetter) error {
+	// Custom launch template and self-managed AMIs are always kept
+	if n.AmiID() != "" || n.AutoScalingGroup != nil {
+		return nil
+	}
+	if n.TargetAMIFamily != "" {
+		warnings.Warnf(`nodegroup "%s": AMI is not pinned, nodegroup is moved to the latest %s EKS optimized AMI`, n.Name(), n.TargetAMIFamily)
+		return nil
+	}
+
+	amis, archs := []string{}, []string{}
+	for _, arch := range n.Architectures() {
+		parameter := releaseAMIParameter(n.AmiType, lo.FromPtr(n.Version), lo.FromPtr(n.ReleaseVersion), arch)
+		if parameter == "" {
+			continue
+		}
+		ami, err := ssmClient.GetParameter(ctx, parameter)
+		if err != nil {
+			return aws.FormatErrorAsMessageOnly(err)
+		}
+		if ami == "" {
+			continue
+		}
+		amis, archs = append(amis, ami), append(archs, arch)
+	}
+
+	if len(amis) == 0 && n.LT != nil && n.LT.ImageId != nil {
+		amis = append(amis, *n.LT.ImageId)
+		if arch := n.amiArchitecture(); arch != "" {
+			archs = append(archs, arch)
+		}
+	}
+	if len(amis) == 0 {
+		warnings.Warnf(`nodegroup "%s": AMI of release version "%s" is not found, the latest EKS optimized AMI is used`, n.Name(), lo.FromPtr(n.ReleaseVersion))
+		return nil
+	}
+	// Instances of an architecture without a pinned AMI could not launch, the NodePool is narrowed to the pinned architectures
+	if len(archs) > 0 && len(archs) < len(n.Architectures()) {
+		warnings.Warnf(`nodegroup "%s": AMI of release version "%s" is only found for %s, NodePool is narrowed to this architecture`,
+			n.Name(), lo.FromPtr(n.ReleaseVersion), strings.Join(archs, ","))
+		n.PinnedArchitectures = archs
+	}
+	n.PinnedAMIs = lo.Uniq(amis)
+	return nil
+}
+
+// Returns the SSM parameter holding the EKS optimized AMI ID of the release version, empty when the AMI type has no versioned parameter
+func releaseAMIParameter(amiType ekstypes.AMITypes, k8sVersion, releaseVersion, arch string) string {
+	ssmArch, ok := ssmArchitectures[arch]
+	if !ok || k8sVersion == "" || releaseVersion == "" {
+		return ""
+	}
+	// AL2 and AL2023 release versions are <Kubernetes version>-<AMI date>
+	_, amiDate, _ := strings.Cut(releaseVersion, "-")
+
+	switch amiType {
+	case ekstypes.AMITypesAl2X8664, ekstypes.AMITypesAl2Arm64:
+		variant, name := "amazon-linux-2", "amazon-eks-node"
+		if arch == "arm64" {
+			variant, name = "amazon-linux-2-arm64", "amazon-eks-arm64-node"
+		}
+		return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/%s/%s-%s-v%s/image_id", k8sVersion, variant, name, k8sVersion, amiDate)
+	case ekstypes.AMITypesAl2X8664Gpu:
+		if arch != "amd64" {
+			return ""
+		}
+		return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/amazon-eks-gpu-node-%s-v%s/image_id", k8sVersion, k8sVersion, amiDate)
+	case ekstypes.AMITypesAl2023X8664Standard, ekstypes.AMITypesAl2023Arm64Standard:
+		return fmt.Sprintf("/aws/service/eks/optimized-ami/%s/amazon-linux-2023/%s/standard/amazon-eks-node-al2023-%s-standard-%s-v%s/image_id",
+			k8sVersion, ssmArch, ssmArch, k8sVersion, amiDate)
+	case ekstypes.AMITypesBottlerocketX8664, ekstypes.AMITypesBottlerocketArm64:
+		// Bottlerocket release versions are Bottlerocket versions
+		return fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s/%s/%s/image_id", k8sVersion, ssmArch, releaseVersion)
+	case ekstypes.AMITypesBottlerocketX8664Nvidia, ekstypes.AMITypesBottlerocketArm64Nvidia:
+		return fmt.Sprintf("/aws/service/bottlerocket/aws-k8s-%s-nvidia/%s/%s/image_id", k8sVersion, ssmArch, releaseVersion)
+	default:
+		// Windows AMIs only have parameters of the latest version
+		return ""
+	}
+}
+
+// Returns architectures of the NodePool, the architectures of the pinned AMIs when AMIs are not found for all of them, both architectures with multi-arch when the AMI supports them,
+// architectures of the instance types otherwise, falling back to the architecture of the AMI type
+func (n NodeGroup) Architectures() []string {
+	if len(n.PinnedArchitectures) > 0 {
+		return n.PinnedArchitectures
+	}
+	if n.MultiArch && n.multiArchAMI() {
+		return []string{instancetype.ArchitectureAmd64, instancetype.ArchitectureArm64}
+	}
+	if archs := n.instanceTypeArchitectures(); len(archs) > 0 {
+		return archs
+	}
+	if strings.Contains(string(n.AmiType), "ARM") {
+		return []string{instancetype.ArchitectureArm64}
+	}
+	return []string{instancetype.ArchitectureAmd64}
+}
+
+// Returns architectures of the instance types of the bundled catalog or the EC2 API, unknown instance types are skipped
+func (n NodeGroup) instanceTypeArchitectures() []string {
+	archs := lo.Uniq(lo.FilterMap(n.InstanceTypes, func(name string, _ int) (string, bool) {
+		info, ok := n.instanceType(name)
+		return info.Architecture, ok && info.Architecture != ""
+	}))
+	sort.Strings(archs)
+	return archs
+}
+
+// Returns the architecture of the EKS AMI type, empty for custom AMIs
+func (n NodeGroup) amiArchitecture() string {
+	switch {
+	case n.AmiID() != "" || n.AmiType == "" || n.AmiType == ekstypes.AMITypesCustom:
+		return ""
+	case strings.Contains(string(n.AmiType), "ARM"):
+		return instancetype.ArchitectureArm64
+	default:
+		return instancetype.ArchitectureAmd64
+	}
+}
+
+// Returns true when the AMI is selected for both architectures, custom AMIs have one architecture and Windows AMIs are amd64 only
+func (n NodeGroup) multiArchAMI() bool {
+	return n.amiArchitecture() != "" && !strings.Contains(string(n.AmiType), "WINDOWS")
+}
+
+// ValidateArchitectures returns an error when the architectures of the AMI and the instance types of the nodegroup conflict
+func (n NodeGroup) ValidateArchitectures() error {
+	archs := n.instanceTypeArchitectures()
+	amiArch := n.amiArchitecture()
+	if amiArch == "" {
+		if len(archs) > 1 {
+			return fmt.Errorf(`nodegroup "%s": instance types %s span %s architectures, the custom AMI supports one architecture`,
+				n.Name(), strings.Join(n.InstanceTypes, ","), strings.Join(archs, " and "))
+		}
+		return nil
+	}
+
+	conflicting := lo.Filter(n.InstanceTypes, func(name string, _ int) bool {
+		info, ok := n.instanceType(name)
+		return ok && info.Architecture != "" && info.Architecture != amiArch
+	})
+	if len(conflicting) > 0 {
+		return fmt.Errorf(`nodegroup "%s": AMI type "%s" is %s, instance types %s are not`, n.Name(), n.AmiType, amiArch, strings.Join(conflicting, ","))
+	}
+	return nil
+}
+
+// Reports nodegroups whose NodePools can not launch instances of both architectures with multi-arch
+func (n NodeGroup) warnMultiArch() {
+	switch {
+	case !n.multiArchAMI():
+		warnings.Warnf(`nodegroup "%s": AMI supports one architecture, NodePool keeps architectures of its instance types`, n.Name())
+	case len(n.InstanceRequirements) == 0 && len(n.instanceTypeArchitectures()) == 1:
+		warnings.Warnf(`nodegroup "%s": instance types are %s, use "--expand-instance-types" to launch instances of both architectures`, n.Name(), n.instanceTypeArchitectures()[0])
+	}
+}
+
+// Returns AMI selector terms of pinned AMIs
+func (n NodeGroup) pinnedAMISelectorTerms() []awskarpenter.AMISelectorTerm {
+	return lo.Map(n.PinnedAMIs, func(ami string, _ int) awskarpenter.AMISelectorTerm {
+		return awskarpenter.AMISelectorTerm{ID: ami}
+	})
+}
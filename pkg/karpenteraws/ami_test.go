@@ -0,0 +1,312 @@
+package karpenteraws
+
+import (
+	"context"
+	"testing"
+
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/punkwalker/karpenter-generate/pkg/instancetype"
+)
+
+type fakeParameterGetter map[string]string
+
+func (f fakeParameterGetter) GetParameter(_ context.Context, name string) (string, error) {
+	return f[name], nil
+}
+
+func TestReleaseAMIParameter(t *testing.T) {
+	tests := []struct {
+		name           string
+		amiType        ekstypes.AMITypes
+		releaseVersion string
+		arch           string
+		expected       string
+	}{
+		{
+			name:           "AL2",
+			amiType:        ekstypes.AMITypesAl2X8664,
+			releaseVersion: "1.29.3-20240506",
+			arch:           "amd64",
+			expected:       "/aws/service/eks/optimized-ami/1.29/amazon-linux-2/amazon-eks-node-1.29-v20240506/image_id",
+		},
+		{
+			name:           "AL2 arm64",
+			amiType:        ekstypes.AMITypesAl2Arm64,
+			releaseVersion: "1.29.3-20240506",
+			arch:           "arm64",
+			expected:       "/aws/service/eks/optimized-ami/1.29/amazon-linux-2-arm64/amazon-eks-arm64-node-1.29-v20240506/image_id",
+		},
+		{
+			name:           "AL2 GPU",
+			amiType:        ekstypes.AMITypesAl2X8664Gpu,
+			releaseVersion: "1.29.3-20240506",
+			arch:           "amd64",
+			expected:       "/aws/service/eks/optimized-ami/1.29/amazon-linux-2-gpu/amazon-eks-gpu-node-1.29-v20240506/image_id",
+		},
+		{
+			name:           "AL2023 arm64",
+			amiType:        ekstypes.AMITypesAl2023Arm64Standard,
+			releaseVersion: "1.29.3-20240506",
+			arch:           "arm64",
+			expected:       "/aws/service/eks/optimized-ami/1.29/amazon-linux-2023/arm64/standard/amazon-eks-node-al2023-arm64-standard-1.29-v20240506/image_id",
+		},
+		{
+			name:           "Bottlerocket",
+			amiType:        ekstypes.AMITypesBottlerocketX8664,
+			releaseVersion: "1.19.5-64049ba8",
+			arch:           "amd64",
+			expected:       "/aws/service/bottlerocket/aws-k8s-1.29/x86_64/1.19.5-64049ba8/image_id",
+		},
+		{
+			name:           "Bottlerocket NVIDIA",
+			amiType:        ekstypes.AMITypesBottlerocketArm64Nvidia,
+			releaseVersion: "1.19.5-64049ba8",
+			arch:           "arm64",
+			expected:       "/aws/service/bottlerocket/aws-k8s-1.29-nvidia/arm64/1.19.5-64049ba8/image_id",
+		},
+		{
+			name:           "Windows",
+			amiType:        ekstypes.AMITypesWindowsCore2022X8664,
+			releaseVersion: "2024.05.14",
+			arch:           "amd64",
+			expected:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, releaseAMIParameter(tt.amiType, "1.29", tt.releaseVersion, tt.arch))
+		})
+	}
+}
+
+func TestNodeGroup_PinAMI(t *testing.T) {
+	parameters := fakeParameterGetter{
+		"/aws/service/eks/optimized-ami/1.29/amazon-linux-2/amazon-eks-node-1.29-v20240506/image_id":             "ami-0123456789abcdef0",
+		"/aws/service/eks/optimized-ami/1.29/amazon-linux-2-arm64/amazon-eks-arm64-node-1.29-v20240506/image_id": "ami-0123456789abcdef1",
+		// Release version whose arm64 AMI is not published
+		"/aws/service/eks/optimized-ami/1.29/amazon-linux-2/amazon-eks-node-1.29-v20240507/image_id": "ami-0123456789abcdef2",
+	}
+	nodegroup := func(amiType ekstypes.AMITypes, releaseVersion string) *ekstypes.Nodegroup {
+		return &ekstypes.Nodegroup{
+			NodegroupName:  lo.ToPtr("ng"),
+			AmiType:        amiType,
+			Version:        lo.ToPtr("1.29"),
+			ReleaseVersion: lo.ToPtr(releaseVersion),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		n        NodeGroup
+		expected []string
+		// Architectures of the NodePool after pinning
+		expectedArchitectures []string
+	}{
+		{
+			name:                  "AMI of release version",
+			n:                     NodeGroup{Nodegroup: nodegroup(ekstypes.AMITypesAl2X8664, "1.29.3-20240506")},
+			expected:              []string{"ami-0123456789abcdef0"},
+			expectedArchitectures: []string{"amd64"},
+		},
+		{
+			name: "Multi-arch AMIs of release version",
+			n: NodeGroup{
+				Nodegroup: nodegroup(ekstypes.AMITypesAl2X8664, "1.29.3-20240506"),
+				MultiArch: true,
+			},
+			expected:              []string{"ami-0123456789abcdef0", "ami-0123456789abcdef1"},
+			expectedArchitectures: []string{"amd64", "arm64"},
+		},
+		{
+			name: "Multi-arch AMI of release version found for one architecture",
+			n: NodeGroup{
+				Nodegroup: nodegroup(ekstypes.AMITypesAl2X8664, "1.29.3-20240507"),
+				MultiArch: true,
+			},
+			expected:              []string{"ami-0123456789abcdef2"},
+			expectedArchitectures: []string{"amd64"},
+		},
+		{
+			name: "Multi-arch image of LT generated by EKS",
+			n: NodeGroup{
+				Nodegroup: nodegroup(ekstypes.AMITypesAl2Arm64, "1.29.0-20240101"),
+				LT:        &ec2types.ResponseLaunchTemplateData{ImageId: lo.ToPtr("ami-0fedcba9876543211")},
+				MultiArch: true,
+			},
+			expected:              []string{"ami-0fedcba9876543211"},
+			expectedArchitectures: []string{"arm64"},
+		},
+		{
+			name: "Image of LT generated by EKS",
+			n: NodeGroup{
+				Nodegroup: nodegroup(ekstypes.AMITypesWindowsCore2022X8664, "2024.05.14"),
+				LT:        &ec2types.ResponseLaunchTemplateData{ImageId: lo.ToPtr("ami-0fedcba9876543210")},
+			},
+			expected:              []string{"ami-0fedcba9876543210"},
+			expectedArchitectures: []string{"amd64"},
+		},
+		{
+			name:                  "Release version not found",
+			n:                     NodeGroup{Nodegroup: nodegroup(ekstypes.AMITypesAl2X8664, "1.29.0-20240101")},
+			expected:              nil,
+			expectedArchitectures: []string{"amd64"},
+		},
+		{
+			name: "Custom LT AMI",
+			n: NodeGroup{
+				Nodegroup: nodegroup(ekstypes.AMITypesCustom, ""),
+				CustomLT:  &ec2types.ResponseLaunchTemplateData{ImageId: lo.ToPtr("ami-0aaaaaaaaaaaaaaa1")},
+			},
+			expected:              nil,
+			expectedArchitectures: []string{"amd64"},
+		},
+		{
+			name: "Self-managed Auto Scaling group",
+			n: NodeGroup{
+				Nodegroup:        nodegroup(ekstypes.AMITypesAl2X8664, "1.29.3-20240506"),
+				AutoScalingGroup: &autoscalingtypes.AutoScalingGroup{},
+			},
+			expected:              nil,
+			expectedArchitectures: []string{"amd64"},
+		},
+		{
+			name: "Target AMI family",
+			n: NodeGroup{
+				Nodegroup:       nodegroup(ekstypes.AMITypesAl2X8664, "1.29.3-20240506"),
+				TargetAMIFamily: "AL2023",
+			},
+			expected:              nil,
+			expectedArchitectures: []string{"amd64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.n.PinAMI(context.Background(), parameters))
+			assert.Equal(t, tt.expected, tt.n.PinnedAMIs)
+			assert.Equal(t, tt.expectedArchitectures, tt.n.Architectures())
+		})
+	}
+}
+
+func TestNodeGroup_Architectures(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        NodeGroup
+		expected []string
+	}{
+		{
+			name: "Graviton instance types with a custom AMI",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				AmiType:       ekstypes.AMITypesCustom,
+				InstanceTypes: []string{"m7g.large", "c7g.large"},
+			}},
+			expected: []string{"arm64"},
+		},
+		{
+			name: "Instance types described with the EC2 API",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesCustom, InstanceTypes: []string{"x2gd.large"}},
+				InstanceTypeInfos: map[string]instancetype.Info{
+					"x2gd.large": {Name: "x2gd.large", Architecture: "arm64"},
+				},
+			},
+			expected: []string{"arm64"},
+		},
+		{
+			name: "Unknown instance types fall back to the AMI type",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				AmiType:       ekstypes.AMITypesAl2023Arm64Standard,
+				InstanceTypes: []string{"x2gd.large"},
+			}},
+			expected: []string{"arm64"},
+		},
+		{
+			name: "Multi-arch",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesAl2023X8664Standard, InstanceTypes: []string{"m5.large"}},
+				MultiArch: true,
+			},
+			expected: []string{"amd64", "arm64"},
+		},
+		{
+			name: "Multi-arch with a Windows AMI",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesWindowsCore2022X8664, InstanceTypes: []string{"m5.large"}},
+				MultiArch: true,
+			},
+			expected: []string{"amd64"},
+		},
+		{
+			name: "Multi-arch with a custom launch template AMI",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesCustom, InstanceTypes: []string{"m5.large"}},
+				CustomLT:  &ec2types.ResponseLaunchTemplateData{ImageId: lo.ToPtr("ami-0123456789abcdef0")},
+				MultiArch: true,
+			},
+			expected: []string{"amd64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.n.Architectures())
+		})
+	}
+}
+
+func TestNodeGroup_ValidateArchitectures(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       NodeGroup
+		wantErr bool
+	}{
+		{
+			name: "Matching architectures",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				NodegroupName: lo.ToPtr("ng"),
+				AmiType:       ekstypes.AMITypesBottlerocketArm64,
+				InstanceTypes: []string{"m7g.large", "x2gd.large"},
+			}},
+		},
+		{
+			name: "AMI type and instance types conflict",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				NodegroupName: lo.ToPtr("ng"),
+				AmiType:       ekstypes.AMITypesAl2X8664,
+				InstanceTypes: []string{"m5.large", "m7g.large"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Custom AMI with instance types of both architectures",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{
+					NodegroupName: lo.ToPtr("ng"),
+					AmiType:       ekstypes.AMITypesCustom,
+					InstanceTypes: []string{"m5.large", "m7g.large"},
+				},
+				AutoScalingGroup: &autoscalingtypes.AutoScalingGroup{},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.n.ValidateArchitectures()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package karpenteraws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+const (
+	EKSNodegroupTagKey        string = "eks:nodegroup-name"
+	NodeTemplateLabelTagKey   string = "k8s.io/cluster-autoscaler/node-template/label/"
+	NodeTemplateTaintTagKey   string = "k8s.io/cluster-autoscaler/node-template/taint/"
+	CapacityRebalanceAnnotKey string = "migrate.karpenter.sh/capacity-rebalance"
+)
+
+// AutoScalingGroupDescriber describes self-managed EC2 Auto Scaling groups, groups which are not found are left out of the result
+type AutoScalingGroupDescriber interface {
+	DescribeAutoScalingGroups(ctx context.Context, names []string, tags map[string]string) ([]autoscalingtypes.AutoScalingGroup, error)
+}
+
+// Returns self-managed Auto Scaling groups selected by name or tag, groups created by EKS Managed Nodegroups are skipped
+func getAutoScalingGroups(ctx context.Context, opts *options.Options, asgClient AutoScalingGroupDescriber) ([]autoscalingtypes.AutoScalingGroup, error) {
+	if len(opts.AutoScalingGroups) == 0 && opts.AutoScalingGroupTag == "" {
+		return nil, nil
+	}
+
+	tags := map[string]string{}
+	if opts.AutoScalingGroupTag != "" {
+		key, val, _ := strings.Cut(opts.AutoScalingGroupTag, "=")
+		tags[key] = val
+	}
+
+	groups, err := asgClient.DescribeAutoScalingGroups(ctx, opts.AutoScalingGroups, tags)
+	if err != nil {
+		return nil, aws.FormatErrorAsMessageOnly(err)
+	}
+
+	return lo.Filter(groups, func(asg autoscalingtypes.AutoScalingGroup, _ int) bool {
+		_, managed := asgTags(&asg)[EKSNodegroupTagKey]
+		return !managed && *asg.AutoScalingGroupName != opts.KarpenterNodegroupName
+	}), nil
+}
+
+// Builds a NodeGroup from a self-managed Auto Scaling group and its launch template
+func NewAutoScalingNodeGroup(ctx context.Context, clusterName string, asg *autoscalingtypes.AutoScalingGroup, ec2Client LaunchTemplateDescriber) (*NodeGroup, error) {
+	name := *asg.AutoScalingGroupName
+	ltSpec := launchTemplateSpecification(asg)
+	if ltSpec == nil || ltSpec.LaunchTemplateId == nil {
+		return nil, fmt.Errorf(`auto scaling group "%s" does not use a launch template, launch configurations are not supported`, name)
+	}
+
+	lt, err := ec2Client.DescribeLaunchTemplateVersions(ctx, *ltSpec.LaunchTemplateId, lo.FromPtr(ltSpec.Version))
+	if err != nil {
+		return nil, aws.FormatErrorAsMessageOnly(err)
+	}
+	if len(lt) == 0 {
+		return nil, fmt.Errorf(`version "%s" of launch template "%s" of auto scaling group "%s" not found`,
+			lo.FromPtr(ltSpec.Version), *ltSpec.LaunchTemplateId, name)
+	}
+	ltData := lt[0].LaunchTemplateData
+
+	instanceTypes := []string{}
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		for _, override := range asg.MixedInstancesPolicy.LaunchTemplate.Overrides {
+			if override.InstanceType != nil {
+				instanceTypes = append(instanceTypes, *override.InstanceType)
+			}
+		}
+	}
+	if len(instanceTypes) == 0 && ltData.InstanceType != "" {
+		instanceTypes = append(instanceTypes, string(ltData.InstanceType))
+	}
+	if len(instanceTypes) == 0 {
+		return nil, fmt.Errorf(`no instance types found for auto scaling group "%s", attribute based instance type selection is not supported`, name)
+	}
+
+	labels := map[string]string{}
+	taints := []ekstypes.Taint{}
+	tags := map[string]string{}
+	for _, tag := range asg.Tags {
+		key, val := lo.FromPtr(tag.Key), lo.FromPtr(tag.Value)
+		switch {
+		case strings.HasPrefix(key, NodeTemplateLabelTagKey):
+			labels[strings.TrimPrefix(key, NodeTemplateLabelTagKey)] = val
+		case strings.HasPrefix(key, NodeTemplateTaintTagKey):
+			taintVal, effect, _ := strings.Cut(val, ":")
+			taints = append(taints, ekstypes.Taint{
+				Key:    lo.ToPtr(strings.TrimPrefix(key, NodeTemplateTaintTagKey)),
+				Value:  lo.ToPtr(taintVal),
+				Effect: taintEffect(effect),
+			})
+		case lo.FromPtr(tag.PropagateAtLaunch):
+			tags[key] = val
+		}
+	}
+
+	return &NodeGroup{
+		Nodegroup: &ekstypes.Nodegroup{
+			NodegroupName: lo.ToPtr(name),
+			ClusterName:   lo.ToPtr(clusterName),
+			Status:        ekstypes.NodegroupStatusActive,
+			AmiType:       ekstypes.AMITypesCustom,
+			InstanceTypes: instanceTypes,
+			Subnets:       lo.Compact(strings.Split(lo.FromPtr(asg.VPCZoneIdentifier), ",")),
+			ScalingConfig: &ekstypes.NodegroupScalingConfig{
+				MinSize:     asg.MinSize,
+				MaxSize:     asg.MaxSize,
+				DesiredSize: asg.DesiredCapacity,
+			},
+			Labels: labels,
+			Taints: taints,
+			Tags:   tags,
+		},
+		CustomLT:         ltData,
+		AutoScalingGroup: asg,
+	}, nil
+}
+
+// Returns tags of the Auto Scaling group as a map
+func asgTags(asg *autoscalingtypes.AutoScalingGroup) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range asg.Tags {
+		tags[lo.FromPtr(tag.Key)] = lo.FromPtr(tag.Value)
+	}
+	return tags
+}
+
+// Cluster Autoscaler node template taints use Kubernetes effect names
+func taintEffect(effect string) ekstypes.TaintEffect {
+	switch effect {
+	case "NoExecute":
+		return ekstypes.TaintEffectNoExecute
+	case "PreferNoSchedule":
+		return ekstypes.TaintEffectPreferNoSchedule
+	default:
+		return ekstypes.TaintEffectNoSchedule
+	}
+}
+
+// ResolveLaunchTemplate sets LT to the launch template EKS generated for the Auto Scaling group of the managed nodegroup,
+// LT is left nil when the Auto Scaling group is not found
+func (n *NodeGroup) ResolveLaunchTemplate(ctx context.Context, asgClient AutoScalingGroupDescriber, ec2Client LaunchTemplateDescriber) error {
+	if n.Resources == nil || len(n.Resources.AutoScalingGroups) == 0 {
+		return nil
+	}
+	names := lo.FilterMap(n.Resources.AutoScalingGroups, func(asg ekstypes.AutoScalingGroup, _ int) (string, bool) {
+		return lo.FromPtr(asg.Name), asg.Name != nil
+	})
+	groups, err := asgClient.DescribeAutoScalingGroups(ctx, names, nil)
+	if err != nil {
+		return aws.FormatErrorAsMessageOnly(err)
+	}
+
+	for _, asg := range groups {
+		ltSpec := launchTemplateSpecification(&asg)
+		if ltSpec == nil || ltSpec.LaunchTemplateId == nil {
+			continue
+		}
+		lt, err := ec2Client.DescribeLaunchTemplateVersions(ctx, *ltSpec.LaunchTemplateId, lo.FromPtr(ltSpec.Version))
+		if err != nil {
+			return aws.FormatErrorAsMessageOnly(err)
+		}
+		if len(lt) == 0 {
+			return fmt.Errorf(`nodegroup "%s": version "%s" of launch template "%s" of auto scaling group "%s" not found`,
+				n.Name(), lo.FromPtr(ltSpec.Version), *ltSpec.LaunchTemplateId, lo.FromPtr(asg.AutoScalingGroupName))
+		}
+		n.LT = lt[0].LaunchTemplateData
+		return nil
+	}
+	return nil
+}
+
+// Returns the launch template of the Auto Scaling group, nil when it uses a launch configuration
+func launchTemplateSpecification(asg *autoscalingtypes.AutoScalingGroup) *autoscalingtypes.LaunchTemplateSpecification {
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		return asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	return asg.LaunchTemplate
+}
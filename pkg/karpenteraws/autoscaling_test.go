@@ -0,0 +1,117 @@
+package karpenteraws
+
+import (
+	"context"
+	"testing"
+
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Serves the launch template versions by launch template ID
+type fakeLaunchTemplateDescriber map[string][]ec2types.LaunchTemplateVersion
+
+func (f fakeLaunchTemplateDescriber) DescribeLaunchTemplateVersions(_ context.Context, id, _ string) ([]ec2types.LaunchTemplateVersion, error) {
+	return f[id], nil
+}
+
+func TestNewAutoScalingNodeGroup(t *testing.T) {
+	launchTemplates := fakeLaunchTemplateDescriber{
+		"lt-1": {{LaunchTemplateId: lo.ToPtr("lt-1"), LaunchTemplateData: &ec2types.ResponseLaunchTemplateData{InstanceType: ec2types.InstanceTypeM5Large}}},
+	}
+	tests := []struct {
+		name              string
+		launchTemplateID  string
+		vpcZoneIdentifier string
+		expectedSubnets   []string
+		err               string
+	}{
+		{
+			name:              "Subnets of the Auto Scaling group",
+			launchTemplateID:  "lt-1",
+			vpcZoneIdentifier: "subnet-a,subnet-b",
+			expectedSubnets:   []string{"subnet-a", "subnet-b"},
+		},
+		{
+			name:             "No subnets",
+			launchTemplateID: "lt-1",
+			expectedSubnets:  []string{},
+		},
+		{
+			name:             "Launch template version not found",
+			launchTemplateID: "lt-missing",
+			err:              `version "$Default" of launch template "lt-missing" of auto scaling group "asg" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asg := &autoscalingtypes.AutoScalingGroup{
+				AutoScalingGroupName: lo.ToPtr("asg"),
+				LaunchTemplate:       &autoscalingtypes.LaunchTemplateSpecification{LaunchTemplateId: lo.ToPtr(tt.launchTemplateID), Version: lo.ToPtr("$Default")},
+				VPCZoneIdentifier:    lo.ToPtr(tt.vpcZoneIdentifier),
+			}
+
+			nodeGroup, err := NewAutoScalingNodeGroup(context.Background(), "my-cluster", asg, launchTemplates)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSubnets, nodeGroup.Subnets)
+		})
+	}
+}
+
+type fakeAutoScalingGroupDescriber []autoscalingtypes.AutoScalingGroup
+
+func (f fakeAutoScalingGroupDescriber) DescribeAutoScalingGroups(_ context.Context, names []string, _ map[string]string) ([]autoscalingtypes.AutoScalingGroup, error) {
+	return lo.Filter(f, func(asg autoscalingtypes.AutoScalingGroup, _ int) bool {
+		return lo.Contains(names, lo.FromPtr(asg.AutoScalingGroupName))
+	}), nil
+}
+
+func TestNodeGroup_ResolveLaunchTemplate(t *testing.T) {
+	launchTemplates := fakeLaunchTemplateDescriber{
+		"lt-1": {{LaunchTemplateId: lo.ToPtr("lt-1"), LaunchTemplateData: &ec2types.ResponseLaunchTemplateData{InstanceType: ec2types.InstanceTypeM5Large}}},
+	}
+	asgs := fakeAutoScalingGroupDescriber{
+		{AutoScalingGroupName: lo.ToPtr("eks-ng-1"), LaunchTemplate: &autoscalingtypes.LaunchTemplateSpecification{LaunchTemplateId: lo.ToPtr("lt-1"), Version: lo.ToPtr("1")}},
+		{AutoScalingGroupName: lo.ToPtr("eks-ng-2"), LaunchTemplate: &autoscalingtypes.LaunchTemplateSpecification{LaunchTemplateId: lo.ToPtr("lt-missing"), Version: lo.ToPtr("1")}},
+	}
+	tests := []struct {
+		name       string
+		asg        string
+		expectedLT bool
+		err        string
+	}{
+		{name: "Generated launch template", asg: "eks-ng-1", expectedLT: true},
+		{name: "Auto Scaling group not found", asg: "eks-other"},
+		{
+			name: "Launch template version not found",
+			asg:  "eks-ng-2",
+			err:  `nodegroup "ng": version "1" of launch template "lt-missing" of auto scaling group "eks-ng-2" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				NodegroupName: lo.ToPtr("ng"),
+				Resources:     &ekstypes.NodegroupResources{AutoScalingGroups: []ekstypes.AutoScalingGroup{{Name: lo.ToPtr(tt.asg)}}},
+			}}
+
+			err := n.ResolveLaunchTemplate(context.Background(), asgs, launchTemplates)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedLT, n.LT != nil)
+		})
+	}
+}
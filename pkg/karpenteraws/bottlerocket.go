@@ -0,0 +1,149 @@
+package karpenteraws
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+// settings.kubernetes keys which Karpenter sets for every Bottlerocket node or which are moved to the NodePool
+var bottlerocketKeysOwnedByKarpenter = []string{
+	"api-server",
+	"cluster-certificate",
+	"cluster-name",
+	"cluster-dns-ip",
+	"max-pods",
+	"node-labels",
+	"node-taints",
+	"eviction-hard",
+	"kube-reserved",
+	"system-reserved",
+	"image-gc-high-threshold-percent",
+	"image-gc-low-threshold-percent",
+	"cpu-cfs-quota-enforced",
+}
+
+// Returns true if the user data is TOML with a settings table, comments and dotted keys (e.g.: settings.kubernetes.max-pods = 110) included
+func isBottlerocketTOML(userData string) bool {
+	raw := map[string]any{}
+	if err := toml.Unmarshal([]byte(userData), &raw); err != nil {
+		return false
+	}
+	_, ok := raw["settings"].(map[string]any)
+	return ok
+}
+
+// ParseBottlerocketUserData moves kubelet settings, labels and taints of Bottlerocket TOML user data to the NodePool
+// and removes settings Karpenter owns, the remaining settings are returned as validated TOML
+func ParseBottlerocketUserData(userData string) (*UserDataSettings, error) {
+	config, err := bootstrap.NewBottlerocketConfig(&userData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Bottlerocket user data: %w", err)
+	}
+	kubernetes := config.Settings.Kubernetes
+
+	settings := &UserDataSettings{
+		AMIFamily: awskarpenter.AMIFamilyBottlerocket,
+		Labels:    map[string]string{},
+	}
+	for key, val := range kubernetes.NodeLabels {
+		if sigkarpenter.IsRestrictedLabel(key) != nil {
+			settings.Unsupported = append(settings.Unsupported, "settings.kubernetes.node-labels."+key)
+			continue
+		}
+		settings.Labels[key] = val
+	}
+	taintKeys := lo.Keys(kubernetes.NodeTaints)
+	sort.Strings(taintKeys)
+	for _, key := range taintKeys {
+		for _, val := range kubernetes.NodeTaints[key] {
+			value, effect, _ := strings.Cut(val, ":")
+			settings.Taints = append(settings.Taints, corev1.Taint{Key: key, Value: value, Effect: corev1.TaintEffect(effect)})
+		}
+	}
+
+	kubeletConfig := map[string]any{}
+	if kubernetes.ClusterDNSIP != nil {
+		kubeletConfig["clusterDNS"] = []string{*kubernetes.ClusterDNSIP}
+	}
+	if kubernetes.MaxPods != nil {
+		kubeletConfig["maxPods"] = *kubernetes.MaxPods
+	}
+	if kubernetes.CPUCFSQuota != nil {
+		kubeletConfig["cpuCFSQuota"] = *kubernetes.CPUCFSQuota
+	}
+	for key, val := range map[string]map[string]string{
+		"evictionHard":   kubernetes.EvictionHard,
+		"kubeReserved":   kubernetes.KubeReserved,
+		"systemReserved": kubernetes.SystemReserved,
+	} {
+		if len(val) > 0 {
+			kubeletConfig[key] = val
+		}
+	}
+	for key, val := range map[string]*string{
+		"imageGCHighThresholdPercent": kubernetes.ImageGCHighThresholdPercent,
+		"imageGCLowThresholdPercent":  kubernetes.ImageGCLowThresholdPercent,
+	} {
+		if val == nil {
+			continue
+		}
+		percent, err := strconv.Atoi(strings.TrimSuffix(*val, "%"))
+		if err != nil {
+			return nil, fmt.Errorf(`invalid Bottlerocket user data: %s "%s" is not a percentage`, key, *val)
+		}
+		kubeletConfig[key] = percent
+	}
+	if settings.Kubelet, _, err = KubeletFromConfig(kubeletConfig); err != nil {
+		return nil, err
+	}
+
+	settings.UserData, err = removeBottlerocketKeys(userData)
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Removes keys owned by Karpenter from settings.kubernetes, unknown settings are kept as-is
+func removeBottlerocketKeys(userData string) (string, error) {
+	raw := map[string]any{}
+	if err := toml.Unmarshal([]byte(userData), &raw); err != nil {
+		return "", fmt.Errorf("invalid Bottlerocket user data: %w", err)
+	}
+
+	if settings, ok := raw["settings"].(map[string]any); ok {
+		if kubernetes, ok := settings["kubernetes"].(map[string]any); ok {
+			for _, key := range bottlerocketKeysOwnedByKarpenter {
+				delete(kubernetes, key)
+			}
+			if len(kubernetes) == 0 {
+				delete(settings, "kubernetes")
+			}
+		}
+		if len(settings) == 0 {
+			delete(raw, "settings")
+		}
+	}
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	data, err := toml.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+	// Karpenter merges user data into its own settings, make sure it can be parsed
+	if _, err := bootstrap.NewBottlerocketConfig(lo.ToPtr(string(data))); err != nil {
+		return "", fmt.Errorf("invalid Bottlerocket user data: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,114 @@
+package karpenteraws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func TestParseBottlerocketUserData(t *testing.T) {
+	tests := []struct {
+		name     string
+		userData string
+		expected *UserDataSettings
+		wantErr  bool
+	}{
+		{
+			name: "Settings owned by Karpenter are removed",
+			userData: `[settings.kubernetes]
+cluster-name = "my-cluster"
+api-server = "https://ABCDEF.gr7.us-west-2.eks.amazonaws.com"
+cluster-certificate = "LS0tLS1CRUdJTg=="
+max-pods = 58
+image-gc-high-threshold-percent = "85"
+allowed-unsafe-sysctls = ["net.core.somaxconn"]
+
+[settings.kubernetes.node-labels]
+team = "data"
+
+[settings.kubernetes.node-taints]
+dedicated = ["data:NoSchedule"]
+
+[settings.kubernetes.kube-reserved]
+cpu = "100m"
+
+[settings.host-containers.admin]
+enabled = true
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "Bottlerocket",
+				Kubelet: &sigkarpenter.KubeletConfiguration{
+					MaxPods:                     lo.ToPtr(int32(58)),
+					KubeReserved:                map[string]string{"cpu": "100m"},
+					ImageGCHighThresholdPercent: lo.ToPtr(int32(85)),
+				},
+				Labels: map[string]string{"team": "data"},
+				Taints: []corev1.Taint{
+					{Key: "dedicated", Value: "data", Effect: corev1.TaintEffectNoSchedule},
+				},
+				UserData: `[settings]
+[settings.host-containers]
+[settings.host-containers.admin]
+enabled = true
+
+[settings.kubernetes]
+allowed-unsafe-sysctls = ['net.core.somaxconn']
+`,
+			},
+		},
+		{
+			name: "Only settings owned by Karpenter",
+			userData: `[settings.kubernetes]
+cluster-name = "my-cluster"
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "Bottlerocket",
+				Labels:    map[string]string{},
+			},
+		},
+		{
+			name: "Invalid setting type",
+			userData: `[settings.kubernetes]
+max-pods = "many"
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBottlerocketUserData(tt.userData)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBottlerocketUserData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseBottlerocketUserData() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsBottlerocketTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		userData string
+		expected bool
+	}{
+		{name: "Settings table", userData: "[settings.kubernetes]\nmax-pods = 110\n", expected: true},
+		{name: "Comment before settings", userData: "# Bottlerocket settings\n[settings.kubernetes]\nmax-pods = 110\n", expected: true},
+		{name: "Dotted keys", userData: "settings.kubernetes.max-pods = 110\nsettings.host-containers.admin.enabled = true\n", expected: true},
+		{name: "Shell script", userData: "#!/bin/bash\n/etc/eks/bootstrap.sh my-cluster\n"},
+		{name: "TOML without settings", userData: "[other]\nkey = 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBottlerocketTOML(tt.userData); got != tt.expected {
+				t.Errorf("isBottlerocketTOML() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
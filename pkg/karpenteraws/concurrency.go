@@ -0,0 +1,85 @@
+package karpenteraws
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Runs fn for indexes 0 to n-1 with at most concurrency calls at a time, no calls are started after an error
+// or once ctx is done, the error of the lowest index is returned so that errors do not depend on the completion order
+func forEachIndex(ctx context.Context, concurrency, n int, fn func(idx int) error) error {
+	concurrency = max(concurrency, 1)
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+	failed := atomic.Bool{}
+	wg := sync.WaitGroup{}
+	launched := 0
+	for ; launched < n && !failed.Load() && ctx.Err() == nil; launched++ {
+		idx := launched
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if errs[idx] = fn(idx); errs[idx] != nil {
+				failed.Store(true)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	if launched < n {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// Describes every launch template version once, concurrent lookups of the same version wait for the first lookup
+type launchTemplateCache struct {
+	LaunchTemplateDescriber
+	mu      sync.Mutex
+	lookups map[string]*launchTemplateLookup
+}
+
+type launchTemplateLookup struct {
+	done     chan struct{}
+	versions []ec2types.LaunchTemplateVersion
+	err      error
+}
+
+func newLaunchTemplateCache(ec2Client LaunchTemplateDescriber) *launchTemplateCache {
+	return &launchTemplateCache{LaunchTemplateDescriber: ec2Client, lookups: map[string]*launchTemplateLookup{}}
+}
+
+// Launch template data of the versions is shared by nodegroups and must not be modified
+func (c *launchTemplateCache) DescribeLaunchTemplateVersions(ctx context.Context, id, version string) ([]ec2types.LaunchTemplateVersion, error) {
+	key := id + "/" + version
+	c.mu.Lock()
+	lookup, found := c.lookups[key]
+	if !found {
+		lookup = &launchTemplateLookup{done: make(chan struct{})}
+		c.lookups[key] = lookup
+	}
+	c.mu.Unlock()
+
+	if found {
+		select {
+		case <-lookup.done:
+			return lookup.versions, lookup.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	lookup.versions, lookup.err = c.LaunchTemplateDescriber.DescribeLaunchTemplateVersions(ctx, id, version)
+	close(lookup.done)
+	return lookup.versions, lookup.err
+}
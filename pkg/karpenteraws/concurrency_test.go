@@ -0,0 +1,80 @@
+package karpenteraws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+)
+
+func TestForEachIndex(t *testing.T) {
+	running, maxRunning := atomic.Int32{}, atomic.Int32{}
+	err := forEachIndex(context.Background(), 3, 20, func(idx int) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		// Later indexes fail first, the error of the lowest index is returned
+		if idx == 5 || idx == 2 {
+			time.Sleep(time.Duration(10-idx) * time.Millisecond)
+			return fmt.Errorf("error %d", idx)
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "error 2" {
+		t.Errorf("forEachIndex() error = %v, expected error 2", err)
+	}
+	if got := maxRunning.Load(); got > 3 {
+		t.Errorf("forEachIndex() ran %d calls at the same time, expected at most 3", got)
+	}
+}
+
+// Counts lookups of launch template versions, lookups are slow so that concurrent lookups overlap
+type countingLaunchTemplateDescriber struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (d *countingLaunchTemplateDescriber) DescribeLaunchTemplateVersions(_ context.Context, id, version string) ([]ec2types.LaunchTemplateVersion, error) {
+	d.mu.Lock()
+	d.calls[id+"/"+version]++
+	d.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	if id == "lt-missing" {
+		return nil, errors.New("launch template not found")
+	}
+	return []ec2types.LaunchTemplateVersion{{LaunchTemplateId: lo.ToPtr(id)}}, nil
+}
+
+func TestLaunchTemplateCache(t *testing.T) {
+	describer := &countingLaunchTemplateDescriber{calls: map[string]int{}}
+	cache := newLaunchTemplateCache(describer)
+	lookups := []string{"lt-1", "lt-1", "lt-2", "lt-1", "lt-missing", "lt-missing"}
+
+	err := forEachIndex(context.Background(), len(lookups), len(lookups), func(idx int) error {
+		versions, err := cache.DescribeLaunchTemplateVersions(context.Background(), lookups[idx], "1")
+		if err == nil && *versions[0].LaunchTemplateId != lookups[idx] {
+			t.Errorf("DescribeLaunchTemplateVersions(%s) returned %s", lookups[idx], *versions[0].LaunchTemplateId)
+		}
+		return err
+	})
+	if err == nil {
+		t.Errorf("DescribeLaunchTemplateVersions() expected error for lt-missing")
+	}
+	expected := map[string]int{"lt-1/1": 1, "lt-2/1": 1, "lt-missing/1": 1}
+	if !reflect.DeepEqual(describer.calls, expected) {
+		t.Errorf("launch template versions described %v times, expected %v", describer.calls, expected)
+	}
+}
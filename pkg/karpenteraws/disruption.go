@@ -0,0 +1,190 @@
+package karpenteraws
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/samber/lo"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+// ConsolidationPolicyWhenEmptyOrUnderutilized is the v1 name of the WhenUnderutilized consolidation policy
+const ConsolidationPolicyWhenEmptyOrUnderutilized = "WhenEmptyOrUnderutilized"
+
+// Pattern of budget nodes enforced by the NodePool CRD
+var budgetNodesRegex = regexp.MustCompile(`^((100|[0-9]{1,2})%|[0-9]+)$`)
+
+// DisruptionPolicy holds disruption settings of all the NodePools and settings of NodePools of specific nodegroups
+type DisruptionPolicy struct {
+	DisruptionSettings
+	// Settings of NodePools of source nodegroups, unset fields fall back to the settings of all the NodePools
+	NodeGroups map[string]DisruptionSettings `json:"nodegroups,omitempty"`
+}
+
+// DisruptionSettings overrides the disruption of NodePools, unset fields keep the generated values
+type DisruptionSettings struct {
+	ConsolidationPolicy string                         `json:"consolidationPolicy,omitempty"`
+	ConsolidateAfter    *sigkarpenter.NillableDuration `json:"consolidateAfter,omitempty"`
+	ExpireAfter         *sigkarpenter.NillableDuration `json:"expireAfter,omitempty"`
+	Budgets             []sigkarpenter.Budget          `json:"budgets,omitempty"`
+}
+
+// LoadDisruptionPolicy reads the disruption policy file of options, disruption flags override its settings of all the NodePools
+func LoadDisruptionPolicy(opts *options.Options) (*DisruptionPolicy, error) {
+	policy := &DisruptionPolicy{}
+	if opts.DisruptionPolicyFile != "" {
+		data, err := os.ReadFile(opts.DisruptionPolicyFile) // #nosec G304
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(data, policy); err != nil {
+			return nil, fmt.Errorf(`failed to parse "%s": %w`, opts.DisruptionPolicyFile, err)
+		}
+	}
+
+	if opts.ConsolidationPolicy != "" {
+		policy.ConsolidationPolicy = opts.ConsolidationPolicy
+	}
+	if opts.ConsolidateAfter != "" {
+		policy.ConsolidateAfter = nillableDuration(opts.ConsolidateAfter)
+	}
+	if opts.ExpireAfter != "" {
+		policy.ExpireAfter = nillableDuration(opts.ExpireAfter)
+	}
+
+	if err := policy.validate(""); err != nil {
+		return nil, err
+	}
+	names := lo.Keys(policy.NodeGroups)
+	sort.Strings(names)
+	for _, name := range names {
+		settings := policy.NodeGroups[name]
+		if err := settings.validate(name); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// Returns the duration of a flag validated in options, "Never" is a nil duration
+func nillableDuration(value string) *sigkarpenter.NillableDuration {
+	duration := &sigkarpenter.NillableDuration{}
+	_ = duration.UnmarshalJSON([]byte(strconv.Quote(value)))
+	return duration
+}
+
+// Returns the settings of the nodegroup, settings of all the NodePools fill the unset fields
+func (p *DisruptionPolicy) settings(nodegroup string) DisruptionSettings {
+	settings := p.DisruptionSettings
+	ng, ok := p.NodeGroups[nodegroup]
+	if !ok {
+		return settings
+	}
+	if ng.ConsolidationPolicy != "" {
+		settings.ConsolidationPolicy = ng.ConsolidationPolicy
+	}
+	if ng.ConsolidateAfter != nil {
+		settings.ConsolidateAfter = ng.ConsolidateAfter
+	}
+	if ng.ExpireAfter != nil {
+		settings.ExpireAfter = ng.ExpireAfter
+	}
+	if len(ng.Budgets) > 0 {
+		settings.Budgets = ng.Budgets
+	}
+	return settings
+}
+
+// Applies the policy to the nodegroups, nodegroups of the policy which are not converted are reported
+func (p *DisruptionPolicy) apply(nodeGroups []*NodeGroup) {
+	names := lo.Map(nodeGroups, func(n *NodeGroup, _ int) string { return n.Name() })
+	for _, nodegroup := range nodeGroups {
+		nodegroup.DisruptionSettings = p.settings(nodegroup.Name())
+	}
+	unknown := lo.Without(lo.Keys(p.NodeGroups), names...)
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		warnings.Warnf(`disruption settings of nodegroup "%s" are not used, the nodegroup is not converted`, name)
+	}
+}
+
+func (s DisruptionSettings) validate(nodegroup string) error {
+	prefix := "disruption settings"
+	if nodegroup != "" {
+		prefix = fmt.Sprintf(`disruption settings of nodegroup "%s"`, nodegroup)
+	}
+	switch s.ConsolidationPolicy {
+	case "", string(sigkarpenter.ConsolidationPolicyWhenEmpty), string(sigkarpenter.ConsolidationPolicyWhenUnderutilized), ConsolidationPolicyWhenEmptyOrUnderutilized:
+	default:
+		return fmt.Errorf(`%s: invalid consolidationPolicy "%s", valid values are "WhenEmpty", "WhenUnderutilized" or "WhenEmptyOrUnderutilized"`, prefix, s.ConsolidationPolicy)
+	}
+	for idx, budget := range s.Budgets {
+		if !budgetNodesRegex.MatchString(budget.Nodes) {
+			return fmt.Errorf(`%s: invalid nodes "%s" of budget %d, specify a number or a percentage (e.g.: 10%%)`, prefix, budget.Nodes, idx)
+		}
+		if (budget.Schedule == nil) != (budget.Duration == nil) {
+			return fmt.Errorf(`%s: schedule and duration of budget %d must be specified together`, prefix, idx)
+		}
+		if budget.Duration != nil && budget.Duration.Duration%time.Minute != 0 {
+			return fmt.Errorf(`%s: duration of budget %d must be in minutes or hours`, prefix, idx)
+		}
+	}
+	return nil
+}
+
+// Returns the disruption of the NodePool, generated values are used for the settings which are not set
+func (n NodeGroup) Disruption() sigkarpenter.Disruption {
+	disruption := sigkarpenter.Disruption{
+		ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenUnderutilized,
+		ConsolidateAfter:    n.DisruptionSettings.ConsolidateAfter,
+		ExpireAfter:         n.ExpireAfter(),
+		Budgets:             n.DisruptionSettings.Budgets,
+	}
+	if n.DisruptionSettings.ConsolidationPolicy == string(sigkarpenter.ConsolidationPolicyWhenEmpty) {
+		disruption.ConsolidationPolicy = sigkarpenter.ConsolidationPolicyWhenEmpty
+	}
+	// consolidateAfter is required with WhenEmpty
+	if disruption.ConsolidationPolicy == sigkarpenter.ConsolidationPolicyWhenEmpty && disruption.ConsolidateAfter == nil {
+		disruption.ConsolidateAfter = &sigkarpenter.NillableDuration{Duration: lo.ToPtr(time.Duration(0))}
+	}
+	if n.DisruptionSettings.ExpireAfter != nil {
+		disruption.ExpireAfter = *n.DisruptionSettings.ExpireAfter
+	}
+	if len(disruption.Budgets) == 0 {
+		disruption.Budgets = n.DefaultBudgets()
+	}
+	return disruption
+}
+
+// Returns a budget disrupting as many nodes as the update of the managed nodegroup, nil to use the Karpenter default
+func (n NodeGroup) DefaultBudgets() []sigkarpenter.Budget {
+	if n.UpdateConfig == nil {
+		return nil
+	}
+	switch {
+	case lo.FromPtr(n.UpdateConfig.MaxUnavailable) > 0:
+		return []sigkarpenter.Budget{{Nodes: strconv.Itoa(int(*n.UpdateConfig.MaxUnavailable))}}
+	case lo.FromPtr(n.UpdateConfig.MaxUnavailablePercentage) > 0:
+		return []sigkarpenter.Budget{{Nodes: fmt.Sprintf("%d%%", *n.UpdateConfig.MaxUnavailablePercentage)}}
+	}
+	return nil
+}
+
+// Returns an error when the disruption of a NodePool can not be expressed in v1beta1
+func validateDisruptionV1beta1(nodePools []sigkarpenter.NodePool) error {
+	for _, np := range nodePools {
+		consolidateAfter := np.Spec.Disruption.ConsolidateAfter
+		if np.Spec.Disruption.ConsolidationPolicy == sigkarpenter.ConsolidationPolicyWhenUnderutilized && consolidateAfter != nil && consolidateAfter.Duration != nil {
+			return fmt.Errorf(`nodepool "%s": consolidateAfter can not be combined with consolidationPolicy WhenUnderutilized in v1beta1, use "--api-version v1"`, np.Name)
+		}
+	}
+	return nil
+}
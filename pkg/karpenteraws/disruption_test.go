@@ -0,0 +1,255 @@
+package karpenteraws
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	karpenterv1 "github.com/punkwalker/karpenter-generate/pkg/apis/v1"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+func duration(d time.Duration) *sigkarpenter.NillableDuration {
+	return &sigkarpenter.NillableDuration{Duration: lo.ToPtr(d)}
+}
+
+func TestLoadDisruptionPolicy(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "disruption.yaml")
+	if err := os.WriteFile(policyFile, []byte(`
+consolidationPolicy: WhenUnderutilized
+expireAfter: 720h
+budgets:
+- nodes: 10%
+nodegroups:
+  stateful:
+    consolidationPolicy: WhenEmpty
+    consolidateAfter: 1h
+    expireAfter: Never
+    budgets:
+    - nodes: "0"
+      schedule: "0 9 * * mon-fri"
+      duration: 8h
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadDisruptionPolicy(&options.Options{DisruptionPolicyFile: policyFile, ExpireAfter: "336h"})
+	if err != nil {
+		t.Fatalf("LoadDisruptionPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		nodegroup string
+		expected  DisruptionSettings
+	}{
+		{
+			name:      "Settings of all the NodePools overridden by flags",
+			nodegroup: "stateless",
+			expected: DisruptionSettings{
+				ConsolidationPolicy: "WhenUnderutilized",
+				ExpireAfter:         duration(336 * time.Hour),
+				Budgets:             []sigkarpenter.Budget{{Nodes: "10%"}},
+			},
+		},
+		{
+			name:      "Settings of the nodegroup",
+			nodegroup: "stateful",
+			expected: DisruptionSettings{
+				ConsolidationPolicy: "WhenEmpty",
+				ConsolidateAfter:    duration(time.Hour),
+				ExpireAfter:         &sigkarpenter.NillableDuration{},
+				Budgets: []sigkarpenter.Budget{{
+					Nodes:    "0",
+					Schedule: lo.ToPtr("0 9 * * mon-fri"),
+					Duration: &metav1.Duration{Duration: 8 * time.Hour},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.settings(tt.nodegroup)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("DisruptionPolicy.settings() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadDisruptionPolicy_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+	}{
+		{name: "Unknown field", policy: "consolidationPolicies: WhenEmpty\n"},
+		{name: "Invalid consolidation policy", policy: "nodegroups:\n  ng:\n    consolidationPolicy: Always\n"},
+		{name: "Invalid budget nodes", policy: "budgets:\n- nodes: one\n"},
+		{name: "Schedule without duration", policy: "budgets:\n- nodes: \"0\"\n  schedule: \"@daily\"\n"},
+		{name: "Duration in seconds", policy: "budgets:\n- nodes: \"0\"\n  schedule: \"@daily\"\n  duration: 30s\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policyFile := filepath.Join(t.TempDir(), "disruption.yaml")
+			if err := os.WriteFile(policyFile, []byte(tt.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadDisruptionPolicy(&options.Options{DisruptionPolicyFile: policyFile}); err == nil {
+				t.Errorf("LoadDisruptionPolicy() expected error")
+			}
+		})
+	}
+}
+
+func TestNodeGroup_Disruption(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          NodeGroup
+		expected   sigkarpenter.Disruption
+		expectedV1 karpenterv1.Disruption
+	}{
+		{
+			name: "Budget of the update config",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				UpdateConfig: &ekstypes.NodegroupUpdateConfig{MaxUnavailable: lo.ToPtr(int32(2))},
+			}},
+			expected: sigkarpenter.Disruption{
+				ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenUnderutilized,
+				Budgets:             []sigkarpenter.Budget{{Nodes: "2"}},
+			},
+			expectedV1: karpenterv1.Disruption{
+				ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized,
+				ConsolidateAfter:    *duration(0),
+				Budgets:             []karpenterv1.Budget{{Nodes: "2"}},
+			},
+		},
+		{
+			name: "Budget percentage of the update config",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				UpdateConfig: &ekstypes.NodegroupUpdateConfig{MaxUnavailablePercentage: lo.ToPtr(int32(20))},
+			}},
+			expected: sigkarpenter.Disruption{
+				ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenUnderutilized,
+				Budgets:             []sigkarpenter.Budget{{Nodes: "20%"}},
+			},
+			expectedV1: karpenterv1.Disruption{
+				ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized,
+				ConsolidateAfter:    *duration(0),
+				Budgets:             []karpenterv1.Budget{{Nodes: "20%"}},
+			},
+		},
+		{
+			name: "WhenEmpty without consolidateAfter",
+			n: NodeGroup{
+				Nodegroup:          &ekstypes.Nodegroup{},
+				DisruptionSettings: DisruptionSettings{ConsolidationPolicy: "WhenEmpty"},
+			},
+			expected: sigkarpenter.Disruption{
+				ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenEmpty,
+				ConsolidateAfter:    duration(0),
+			},
+			expectedV1: karpenterv1.Disruption{
+				ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmpty,
+				ConsolidateAfter:    *duration(0),
+			},
+		},
+		{
+			name: "Settings override the update config and the instance lifetime",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{
+					UpdateConfig: &ekstypes.NodegroupUpdateConfig{MaxUnavailable: lo.ToPtr(int32(2))},
+				},
+				AutoScalingGroup: &autoscalingtypes.AutoScalingGroup{MaxInstanceLifetime: lo.ToPtr(int32(86400))},
+				DisruptionSettings: DisruptionSettings{
+					ConsolidationPolicy: "WhenEmptyOrUnderutilized",
+					ConsolidateAfter:    duration(10 * time.Minute),
+					ExpireAfter:         &sigkarpenter.NillableDuration{},
+					Budgets:             []sigkarpenter.Budget{{Nodes: "1"}},
+				},
+			},
+			expected: sigkarpenter.Disruption{
+				ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenUnderutilized,
+				ConsolidateAfter:    duration(10 * time.Minute),
+				Budgets:             []sigkarpenter.Budget{{Nodes: "1"}},
+			},
+			expectedV1: karpenterv1.Disruption{
+				ConsolidationPolicy: karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized,
+				ConsolidateAfter:    *duration(10 * time.Minute),
+				Budgets:             []karpenterv1.Budget{{Nodes: "1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, apiVersion := range []string{options.APIVersionV1beta1, options.APIVersionV1} {
+			t.Run(tt.name+"/"+apiVersion, func(t *testing.T) {
+				got := tt.n.Disruption()
+				if apiVersion == options.APIVersionV1 {
+					gotV1 := NodePoolV1(sigkarpenter.NodePool{Spec: sigkarpenter.NodePoolSpec{Disruption: got}}).Spec.Disruption
+					if !reflect.DeepEqual(gotV1, tt.expectedV1) {
+						t.Errorf("NodePoolV1() disruption = %+v, expected %+v", gotV1, tt.expectedV1)
+					}
+					return
+				}
+				if !reflect.DeepEqual(got, tt.expected) {
+					t.Errorf("NodeGroup.Disruption() = %+v, expected %+v", got, tt.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateFromNodeGroups_Disruption(t *testing.T) {
+	nodeGroups := func() []*NodeGroup {
+		return lo.Map([]string{"stateful", "stateless"}, func(name string, _ int) *NodeGroup {
+			return &NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{
+					NodegroupName: lo.ToPtr(name),
+					ClusterName:   lo.ToPtr("my-cluster"),
+					NodeRole:      lo.ToPtr("arn:aws:iam::123456789012:role/eks-node-role"),
+					AmiType:       ekstypes.AMITypesAl2X8664,
+					InstanceTypes: []string{"m5.large"},
+					Subnets:       []string{"subnet-0a1b2c3d4e5f60001"},
+					DiskSize:      lo.ToPtr(int32(20)),
+				},
+				ClusterSecurityGroupID: "sg-0c0c0c0c0c0c0c0c1",
+			}
+		})
+	}
+	policyFile := filepath.Join(t.TempDir(), "disruption.yaml")
+	if err := os.WriteFile(policyFile, []byte("nodegroups:\n  stateful:\n    consolidationPolicy: WhenEmpty\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	objs, err := GenerateFromNodeGroups(&options.Options{DisruptionPolicyFile: policyFile}, nodeGroups())
+	if err != nil {
+		t.Fatalf("GenerateFromNodeGroups() error = %v", err)
+	}
+	nodePools := lo.FilterMap(objs, func(obj runtime.Object, _ int) (*sigkarpenter.NodePool, bool) {
+		np, ok := obj.(*sigkarpenter.NodePool)
+		return np, ok
+	})
+	if len(nodePools) != 2 {
+		t.Errorf("GenerateFromNodeGroups() returned %d NodePools, nodegroups with different disruption are not merged", len(nodePools))
+	}
+
+	opts := &options.Options{ConsolidationPolicy: "WhenUnderutilized", ConsolidateAfter: "10m"}
+	if _, err := GenerateFromNodeGroups(opts, nodeGroups()); err == nil {
+		t.Errorf("GenerateFromNodeGroups() expected error for consolidateAfter with WhenUnderutilized in v1beta1")
+	}
+	opts.APIVersion = options.APIVersionV1
+	if _, err := GenerateFromNodeGroups(opts, nodeGroups()); err != nil {
+		t.Errorf("GenerateFromNodeGroups() v1 error = %v", err)
+	}
+}
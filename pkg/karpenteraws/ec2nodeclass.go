@@ -3,14 +3,19 @@ package karpenteraws
 import (
 	"context"
 	"encoding/base64"
+	"fmt"
+	"sort"
 	"strings"
 
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	awskarpenterprovider "github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/samber/lo"
 	k8sapiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
 )
 
 const (
@@ -18,7 +23,7 @@ const (
 	ClusterTagKey                    string = "kubernetes.io/cluster/"
 	ALAndBottleRocketDefaultDiskSize int32  = 20
 	WindowsDefaultDiskSize           int32  = 50
-	TagLabelPattern                  string = `^(aws:|eksctl|alpha\.eksctl\.io|Name)`
+	TagLabelPattern                  string = `^(aws:|eks:|eksctl|alpha\.eksctl\.io|Name|kubernetes\.io/cluster/|k8s\.io/cluster-autoscaler/)`
 )
 
 var (
@@ -45,8 +50,8 @@ func (n NodeGroup) Name() string {
 	return strings.ToLower(*n.NodegroupName)
 }
 
+// Returns the AMI of the custom launch template, AMIs of managed nodegroups are pinned with PinAMI
 func (n NodeGroup) AmiID() string {
-	// TODO: implement preserve AMIId for MNG
 	if n.CustomLT != nil {
 		if n.CustomLT.ImageId != nil {
 			return *n.CustomLT.ImageId
@@ -71,6 +76,7 @@ func (n NodeGroup) NodeClassSpec() awskarpenter.EC2NodeClassSpec {
 	return awskarpenter.EC2NodeClassSpec{
 		AMIFamily:                  n.AMIFamily(),
 		Role:                       n.Role(),
+		InstanceProfile:            n.InstanceProfile(),
 		AMISelectorTerms:           n.AMISelectorTerms(),
 		SubnetSelectorTerms:        n.SubnetSelectorTerms(),
 		SecurityGroupSelectorTerms: n.SecurityGroupSelectorTerms(),
@@ -89,9 +95,12 @@ func (n NodeGroup) FilteredTags() map[string]string {
 		}
 	}
 
-	// Custom Launch Template tags take precedence
-	if n.CustomLT != nil {
-		for _, tagspec := range n.CustomLT.TagSpecifications {
+	// Launch Template tags take precedence, Custom Launch Template tags over the ones EKS set
+	for _, lt := range []*ec2types.ResponseLaunchTemplateData{n.LT, n.CustomLT} {
+		if lt == nil {
+			continue
+		}
+		for _, tagspec := range lt.TagSpecifications {
 			for _, tag := range tagspec.Tags {
 				if !tagLabeltoOmmit(*tag.Key) {
 					filteredTags[*tag.Key] = *tag.Value
@@ -103,6 +112,9 @@ func (n NodeGroup) FilteredTags() map[string]string {
 }
 
 func (n NodeGroup) AMIFamily() *string {
+	if n.TargetAMIFamily != "" {
+		return lo.ToPtr(n.TargetAMIFamily)
+	}
 	switch n.AmiType {
 	case ekstypes.AMITypesAl2X8664, ekstypes.AMITypesAl2X8664Gpu, ekstypes.AMITypesAl2Arm64:
 		return lo.ToPtr(awskarpenter.AMIFamilyAL2)
@@ -115,32 +127,62 @@ func (n NodeGroup) AMIFamily() *string {
 	case ekstypes.AMITypesAl2023X8664Standard, ekstypes.AMITypesAl2023Arm64Standard:
 		return lo.ToPtr(awskarpenter.AMIFamilyAL2023)
 	default:
+		// Custom AMIs bootstrapped with bootstrap.sh or nodeadm are bootstrapped by Karpenter
+		if n.UserDataSettings != nil && n.UserDataSettings.AMIFamily != "" {
+			return lo.ToPtr(n.UserDataSettings.AMIFamily)
+		}
 		return lo.ToPtr(awskarpenter.AMIFamilyCustom)
 	}
 }
 
 func (n NodeGroup) AMISelectorTerms() []awskarpenter.AMISelectorTerm {
 	amiTerms := []awskarpenter.AMISelectorTerm{}
-	if n.CustomLT != nil {
+	// AMIs of the source nodegroup do not match the target AMI family
+	if n.CustomLT != nil && n.TargetAMIFamily == "" {
 		if n.CustomLT.ImageId != nil {
 			amiTerms = append(amiTerms, awskarpenter.AMISelectorTerm{
 				ID: n.AmiID(),
 			})
 		}
 	}
+	if len(amiTerms) == 0 {
+		amiTerms = n.pinnedAMISelectorTerms()
+	}
 	return amiTerms
 }
 
 func (n NodeGroup) Role() string {
 	// TODO: Implement override for MNG nodeRole
+	if n.NodeRole == nil {
+		return ""
+	}
 	roleName := *n.NodeRole
 	lastIdx := strings.LastIndex(*n.NodeRole, "/")
 	roleName = roleName[lastIdx+1:]
 	return roleName
 }
 
+// Returns the instance profile of self-managed nodegroups which do not have a node role
+func (n NodeGroup) InstanceProfile() *string {
+	if n.NodeRole != nil || n.CustomLT == nil || n.CustomLT.IamInstanceProfile == nil {
+		return nil
+	}
+	if n.CustomLT.IamInstanceProfile.Name != nil {
+		return n.CustomLT.IamInstanceProfile.Name
+	}
+	if arn := lo.FromPtr(n.CustomLT.IamInstanceProfile.Arn); arn != "" {
+		return lo.ToPtr(arn[strings.LastIndex(arn, "/")+1:])
+	}
+	return nil
+}
+
 func (n NodeGroup) SubnetSelectorTerms() []awskarpenter.SubnetSelectorTerm {
 	subnetSlice := []awskarpenter.SubnetSelectorTerm{}
+	if len(n.SubnetTags) > 0 {
+		return append(subnetSlice, awskarpenter.SubnetSelectorTerm{
+			Tags: n.SubnetTags,
+		})
+	}
 	for _, subnet := range n.Subnets {
 		subnetSlice = append(subnetSlice, awskarpenter.SubnetSelectorTerm{
 			ID: subnet,
@@ -149,51 +191,64 @@ func (n NodeGroup) SubnetSelectorTerms() []awskarpenter.SubnetSelectorTerm {
 	return subnetSlice
 }
 
+// Returns security groups of the custom launch template, or of the launch template EKS generated,
+// falling back to the cluster security group and the remote access security group of the nodegroup
 func (n NodeGroup) SecurityGroupSelectorTerms() []awskarpenter.SecurityGroupSelectorTerm {
-	sgTerms := []awskarpenter.SecurityGroupSelectorTerm{}
-
-	// For customLaunchTemplates
-	if n.CustomLT != nil {
-		if n.CustomLT.SecurityGroupIds != nil {
-			ltSG := n.CustomLT.SecurityGroupIds
-			for _, sg := range ltSG {
-				sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
-					ID: sg,
-				})
-			}
-		}
-		if n.CustomLT.SecurityGroups != nil {
-			ltSG := n.CustomLT.SecurityGroups
-			for _, sg := range ltSG {
-				sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
-					Name: sg,
-				})
-			}
+	for _, lt := range []*ec2types.ResponseLaunchTemplateData{n.CustomLT, n.LT} {
+		if sgTerms := launchTemplateSecurityGroups(lt); len(sgTerms) > 0 {
+			return sgTerms
 		}
-		if n.CustomLT.NetworkInterfaces != nil {
-			for _, eni := range n.CustomLT.NetworkInterfaces {
-				if eni.Groups != nil {
-					for _, sg := range eni.Groups {
-						sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
-							ID: sg,
-						})
-					}
-				}
-			}
+	}
+
+	sgTerms := []awskarpenter.SecurityGroupSelectorTerm{}
+	if n.ClusterSecurityGroupID != "" {
+		sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
+			ID: n.ClusterSecurityGroupID,
+		})
+		if n.Resources != nil && n.Resources.RemoteAccessSecurityGroup != nil {
+			sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
+				ID: *n.Resources.RemoteAccessSecurityGroup,
+			})
 		}
+		return sgTerms
 	}
 
 	// Use clusterTag as SecurityGroupSelector if no SGs found
-	if len(sgTerms) == 0 {
+	return append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
+		Tags: ClusterTag,
+	})
+}
+
+func launchTemplateSecurityGroups(lt *ec2types.ResponseLaunchTemplateData) []awskarpenter.SecurityGroupSelectorTerm {
+	sgTerms := []awskarpenter.SecurityGroupSelectorTerm{}
+	if lt == nil {
+		return sgTerms
+	}
+	for _, sg := range lt.SecurityGroupIds {
+		sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
+			ID: sg,
+		})
+	}
+	for _, sg := range lt.SecurityGroups {
 		sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
-			Tags: ClusterTag,
+			Name: sg,
 		})
 	}
+	for _, eni := range lt.NetworkInterfaces {
+		for _, sg := range eni.Groups {
+			sgTerms = append(sgTerms, awskarpenter.SecurityGroupSelectorTerm{
+				ID: sg,
+			})
+		}
+	}
 	return sgTerms
 }
 
 // Returns UserData for nodegroup if Custom Launch Template is used with MNG
 func (n NodeGroup) UserData() *string {
+	if n.UserDataSettings != nil {
+		return lo.EmptyableToPtr(n.UserDataSettings.UserData)
+	}
 	if n.CustomLT != nil && n.CustomLT.UserData != nil {
 		decodedUserData, _ := base64.StdEncoding.DecodeString(*n.CustomLT.UserData)
 		return lo.ToPtr(string(decodedUserData))
@@ -203,30 +258,20 @@ func (n NodeGroup) UserData() *string {
 
 // Returns AWS Karpenter BlockDeviceMappings for nodegroup if Custom Launch Template is used with MNG or Custom DiskSize is configured
 func (n NodeGroup) BlockDeviceMappings() []*awskarpenter.BlockDeviceMapping {
-	mappings := []*awskarpenter.BlockDeviceMapping{}
-	var diskSize *k8sapiresource.Quantity
-
 	if n.CustomLT != nil {
-		for _, mapping := range n.CustomLT.BlockDeviceMappings {
-			if mapping.Ebs != nil {
-				bMap := &awskarpenter.BlockDeviceMapping{
-					DeviceName: lo.EmptyableToPtr(lo.FromPtr(mapping.DeviceName)),
-					EBS: &awskarpenter.BlockDevice{
-						VolumeSize:          k8sapiresource.NewQuantity(int64(*mapping.Ebs.VolumeSize)*GiB, k8sapiresource.BinarySI),
-						VolumeType:          lo.ToPtr(string(mapping.Ebs.VolumeType)),
-						DeleteOnTermination: mapping.Ebs.DeleteOnTermination,
-						Encrypted:           mapping.Ebs.Encrypted,
-						IOPS:                lo.EmptyableToPtr(int64(lo.FromPtr(mapping.Ebs.Iops))),
-						KMSKeyID:            mapping.Ebs.KmsKeyId,
-						SnapshotID:          mapping.Ebs.SnapshotId,
-						Throughput:          lo.EmptyableToPtr(int64(lo.FromPtr(mapping.Ebs.Throughput))),
-					},
-				}
-				mappings = append(mappings, bMap)
-			}
-		}
-		return mappings
+		return launchTemplateBlockDeviceMappings(n.CustomLT)
 	}
+	mappings := n.defaultBlockDeviceMappings()
+	// Fields set in the Launch Template generated by EKS (e.g.: volume type) override the defaults of the AMI family
+	if n.LT != nil {
+		mappings = mergeBlockDeviceMappings(mappings, launchTemplateBlockDeviceMappings(n.LT))
+	}
+	return mappings
+}
+
+// Returns the block device mappings of the AMI family with the disk size of the nodegroup
+func (n NodeGroup) defaultBlockDeviceMappings() []*awskarpenter.BlockDeviceMapping {
+	var diskSize *k8sapiresource.Quantity
 
 	// Managed Node Group DiskSize - https: //docs.aws.amazon.com/eks/latest/APIReference/API_CreateNodegroup.html#AmazonEKS-CreateNodegroup-request-diskSize
 	if *n.DiskSize != ALAndBottleRocketDefaultDiskSize && (*n.AMIFamily() == awskarpenter.AMIFamilyAL2 || *n.AMIFamily() == awskarpenter.AMIFamilyAL2023 || n.AMIFamily() == &awskarpenter.AMIFamilyBottlerocket) {
@@ -238,7 +283,7 @@ func (n NodeGroup) BlockDeviceMappings() []*awskarpenter.BlockDeviceMapping {
 
 	// Update the diskSize in default mappings if value is Set
 	amiFamily := awskarpenterprovider.GetAMIFamily(n.AMIFamily(), &awskarpenterprovider.Options{})
-	mappings = amiFamily.DefaultBlockDeviceMappings()
+	mappings := amiFamily.DefaultBlockDeviceMappings()
 	if diskSize != nil {
 		for _, mapping := range mappings {
 			mapping.EBS.VolumeSize = diskSize
@@ -247,16 +292,119 @@ func (n NodeGroup) BlockDeviceMappings() []*awskarpenter.BlockDeviceMapping {
 	return mappings
 }
 
+// Overrides the fields of the mappings of the same device which are set in overrides, other devices are appended
+func mergeBlockDeviceMappings(mappings, overrides []*awskarpenter.BlockDeviceMapping) []*awskarpenter.BlockDeviceMapping {
+	for _, override := range overrides {
+		mapping, found := lo.Find(mappings, func(mapping *awskarpenter.BlockDeviceMapping) bool {
+			return lo.FromPtr(mapping.DeviceName) == lo.FromPtr(override.DeviceName)
+		})
+		if !found || mapping.EBS == nil {
+			mappings = append(mappings, override)
+			continue
+		}
+		mapping.EBS = &awskarpenter.BlockDevice{
+			VolumeSize:          orDefault(override.EBS.VolumeSize, mapping.EBS.VolumeSize),
+			VolumeType:          orDefault(override.EBS.VolumeType, mapping.EBS.VolumeType),
+			DeleteOnTermination: orDefault(override.EBS.DeleteOnTermination, mapping.EBS.DeleteOnTermination),
+			Encrypted:           orDefault(override.EBS.Encrypted, mapping.EBS.Encrypted),
+			IOPS:                orDefault(override.EBS.IOPS, mapping.EBS.IOPS),
+			KMSKeyID:            orDefault(override.EBS.KMSKeyID, mapping.EBS.KMSKeyID),
+			SnapshotID:          orDefault(override.EBS.SnapshotID, mapping.EBS.SnapshotID),
+			Throughput:          orDefault(override.EBS.Throughput, mapping.EBS.Throughput),
+		}
+	}
+	return mappings
+}
+
+func orDefault[T any](val, fallback *T) *T {
+	if val != nil {
+		return val
+	}
+	return fallback
+}
+
+func launchTemplateBlockDeviceMappings(lt *ec2types.ResponseLaunchTemplateData) []*awskarpenter.BlockDeviceMapping {
+	mappings := []*awskarpenter.BlockDeviceMapping{}
+	for _, mapping := range lt.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		var volumeSize *k8sapiresource.Quantity
+		if mapping.Ebs.VolumeSize != nil {
+			volumeSize = k8sapiresource.NewQuantity(int64(*mapping.Ebs.VolumeSize)*GiB, k8sapiresource.BinarySI)
+		}
+		mappings = append(mappings, &awskarpenter.BlockDeviceMapping{
+			DeviceName: lo.EmptyableToPtr(lo.FromPtr(mapping.DeviceName)),
+			EBS: &awskarpenter.BlockDevice{
+				VolumeSize:          volumeSize,
+				VolumeType:          lo.EmptyableToPtr(string(mapping.Ebs.VolumeType)),
+				DeleteOnTermination: mapping.Ebs.DeleteOnTermination,
+				Encrypted:           mapping.Ebs.Encrypted,
+				IOPS:                lo.EmptyableToPtr(int64(lo.FromPtr(mapping.Ebs.Iops))),
+				KMSKeyID:            mapping.Ebs.KmsKeyId,
+				SnapshotID:          mapping.Ebs.SnapshotId,
+				Throughput:          lo.EmptyableToPtr(int64(lo.FromPtr(mapping.Ebs.Throughput))),
+			},
+		})
+	}
+	return mappings
+}
+
+// Returns metadata options of the Custom Launch Template or of the Launch Template generated by EKS
 func (n NodeGroup) MetadataOptions() *awskarpenter.MetadataOptions {
-	if n.CustomLT != nil {
-		if n.CustomLT.MetadataOptions != nil {
-			metaOptions := &awskarpenter.MetadataOptions{
-				HTTPEndpoint:            lo.EmptyableToPtr(string(lo.FromPtr(&n.CustomLT.MetadataOptions.HttpEndpoint))),
-				HTTPPutResponseHopLimit: lo.EmptyableToPtr(int64(lo.FromPtr(n.CustomLT.MetadataOptions.HttpPutResponseHopLimit))),
-				HTTPTokens:              lo.EmptyableToPtr(string(lo.FromPtr(&n.CustomLT.MetadataOptions.HttpTokens))),
-				HTTPProtocolIPv6:        lo.EmptyableToPtr(string(lo.FromPtr(&n.CustomLT.MetadataOptions.HttpProtocolIpv6))),
+	for _, lt := range []*ec2types.ResponseLaunchTemplateData{n.CustomLT, n.LT} {
+		if lt != nil && lt.MetadataOptions != nil {
+			return &awskarpenter.MetadataOptions{
+				HTTPEndpoint:            lo.EmptyableToPtr(string(lt.MetadataOptions.HttpEndpoint)),
+				HTTPPutResponseHopLimit: lo.EmptyableToPtr(int64(lo.FromPtr(lt.MetadataOptions.HttpPutResponseHopLimit))),
+				HTTPTokens:              lo.EmptyableToPtr(string(lt.MetadataOptions.HttpTokens)),
+				HTTPProtocolIPv6:        lo.EmptyableToPtr(string(lt.MetadataOptions.HttpProtocolIpv6)),
 			}
-			return metaOptions
+		}
+	}
+	return nil
+}
+
+// Lifts kubelet flags, labels and taints out of the custom launch template user data and converts
+// the nodegroup to the target AMI family if set, Windows user data is not parsed
+func (n *NodeGroup) MigrateUserData(targetAMIFamily string) error {
+	if n.CustomLT != nil && n.CustomLT.UserData != nil && !strings.HasPrefix(string(n.AmiType), "WINDOWS") {
+		decodedUserData, err := base64.StdEncoding.DecodeString(*n.CustomLT.UserData)
+		if err != nil {
+			return fmt.Errorf(`failed to decode user data of nodegroup "%s": %w`, n.Name(), err)
+		}
+
+		parse := ParseUserData
+		if strings.HasPrefix(string(n.AmiType), "BOTTLEROCKET") || isBottlerocketTOML(string(decodedUserData)) {
+			parse = ParseBottlerocketUserData
+		}
+		settings, err := parse(string(decodedUserData))
+		if err != nil {
+			return fmt.Errorf(`nodegroup "%s": %w`, n.Name(), err)
+		}
+		n.UserDataSettings = settings
+	}
+
+	if targetAMIFamily == awskarpenter.AMIFamilyAL2023 {
+		if err := n.convertToAL2023(); err != nil {
+			return fmt.Errorf(`nodegroup "%s": %w`, n.Name(), err)
+		}
+	}
+
+	if n.UserDataSettings != nil {
+		unsupported := append(append([]string{}, n.UserDataSettings.Unsupported...), n.UserDataSettings.KubeletFlags...)
+		for arg, val := range n.UserDataSettings.BootstrapArgs {
+			unsupported = append(unsupported, "--"+arg+" "+val)
+		}
+		sort.Strings(unsupported)
+		for _, setting := range unsupported {
+			warnings.Warnf(`nodegroup "%s": "%s" in user data is not supported by Karpenter and is not migrated`, n.Name(), setting)
+		}
+		for _, line := range n.UserDataSettings.BootstrapLines {
+			warnings.Warnf(`nodegroup "%s": "%s" in user data runs bootstrap.sh with other commands, the line is kept and its arguments are not migrated`, n.Name(), line)
+		}
+		for _, arg := range n.UserDataSettings.UnresolvedBootstrapArgs {
+			warnings.Warnf(`nodegroup "%s": "%s" in user data uses a shell variable which is not assigned in the script, bootstrap.sh is kept and its arguments are not migrated`, n.Name(), arg)
 		}
 	}
 	return nil
@@ -11,6 +11,9 @@ import (
 	"github.com/samber/lo"
 	k8sapiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	karpenterv1 "github.com/punkwalker/karpenter-generate/pkg/apis/v1"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
 )
 
 func TestNodeGroup_Name(t *testing.T) {
@@ -125,6 +128,42 @@ func TestNodeGroup_FilteredTags(t *testing.T) {
 		n    NodeGroup
 		want map[string]string
 	}{
+		{
+			name: "Custom LT tags take precedence over LT tags",
+			n: NodeGroup{
+				LT: &ec2types.ResponseLaunchTemplateData{
+					TagSpecifications: []ec2types.LaunchTemplateTagSpecification{
+						{
+							Tags: []ec2types.Tag{
+								{Key: lo.ToPtr("eks:cluster-name"), Value: lo.ToPtr("my-cluster")},
+								{Key: lo.ToPtr("eks:nodegroup-name"), Value: lo.ToPtr("ng")},
+								{Key: lo.ToPtr("env"), Value: lo.ToPtr("staging")},
+								{Key: lo.ToPtr("cost-center"), Value: lo.ToPtr("1234")},
+							},
+						},
+					},
+				},
+				CustomLT: &ec2types.ResponseLaunchTemplateData{
+					TagSpecifications: []ec2types.LaunchTemplateTagSpecification{
+						{
+							Tags: []ec2types.Tag{
+								{Key: lo.ToPtr("env"), Value: lo.ToPtr("prod")},
+							},
+						},
+					},
+				},
+				Nodegroup: &ekstypes.Nodegroup{
+					Tags: map[string]string{
+						"team": "engineering",
+					},
+				},
+			},
+			want: map[string]string{
+				"env":         "prod",
+				"team":        "engineering",
+				"cost-center": "1234",
+			},
+		},
 		{
 			name: "No custom tags, no AWS tags",
 			n: NodeGroup{
@@ -333,13 +372,15 @@ func TestNodeGroup_AMIFamily(t *testing.T) {
 
 func TestNodeGroup_AMISelectorTerms(t *testing.T) {
 	tests := []struct {
-		name string
-		n    NodeGroup
-		want []awskarpenter.AMISelectorTerm
+		name   string
+		n      NodeGroup
+		want   []awskarpenter.AMISelectorTerm
+		wantV1 []karpenterv1.AMISelectorTerm
 	}{
 		{
 			name: "Valid AMI ID",
 			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesCustom},
 				CustomLT: &ec2types.ResponseLaunchTemplateData{
 					ImageId: lo.ToPtr("ami-0123456789abcdef"),
 				},
@@ -349,30 +390,63 @@ func TestNodeGroup_AMISelectorTerms(t *testing.T) {
 					ID: "ami-0123456789abcdef",
 				},
 			},
+			wantV1: []karpenterv1.AMISelectorTerm{{ID: "ami-0123456789abcdef"}},
 		},
 		{
 			name: "Nil CustomLT",
 			n: NodeGroup{
-				CustomLT: nil,
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesAl2023X8664Standard},
+				CustomLT:  nil,
 			},
-			want: []awskarpenter.AMISelectorTerm{},
+			want:   []awskarpenter.AMISelectorTerm{},
+			wantV1: []karpenterv1.AMISelectorTerm{{Alias: "al2023@latest"}},
 		},
 		{
 			name: "Nil ImageId",
 			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesBottlerocketX8664},
 				CustomLT: &ec2types.ResponseLaunchTemplateData{
 					ImageId: nil,
 				},
 			},
-			want: []awskarpenter.AMISelectorTerm{},
+			want:   []awskarpenter.AMISelectorTerm{},
+			wantV1: []karpenterv1.AMISelectorTerm{{Alias: "bottlerocket@latest"}},
+		},
+		{
+			name: "Pinned AMIs",
+			n: NodeGroup{
+				Nodegroup:  &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesAl2X8664},
+				PinnedAMIs: []string{"ami-0123456789abcdef"},
+			},
+			want: []awskarpenter.AMISelectorTerm{
+				{
+					ID: "ami-0123456789abcdef",
+				},
+			},
+			wantV1: []karpenterv1.AMISelectorTerm{{ID: "ami-0123456789abcdef"}},
+		},
+		{
+			name:   "Custom AMI family without terms",
+			n:      NodeGroup{Nodegroup: &ekstypes.Nodegroup{AmiType: ekstypes.AMITypesCustom}},
+			want:   []awskarpenter.AMISelectorTerm{},
+			wantV1: []karpenterv1.AMISelectorTerm{},
 		},
 	}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.n.AMISelectorTerms(); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NodeGroup.AMISelectorTerms() = %v, want %v", got, tt.want)
-			}
-		})
+		for _, apiVersion := range []string{options.APIVersionV1beta1, options.APIVersionV1} {
+			t.Run(tt.name+"/"+apiVersion, func(t *testing.T) {
+				got := tt.n.AMISelectorTerms()
+				if apiVersion == options.APIVersionV1 {
+					if gotV1 := AMISelectorTermsV1(tt.n.AMIFamily(), got); !reflect.DeepEqual(gotV1, tt.wantV1) {
+						t.Errorf("AMISelectorTermsV1() = %v, want %v", gotV1, tt.wantV1)
+					}
+					return
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("NodeGroup.AMISelectorTerms() = %v, want %v", got, tt.want)
+				}
+			})
+		}
 	}
 }
 
@@ -445,6 +519,20 @@ func TestNodeGroup_SubnetSelectorTerms(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Subnet tags",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{
+					Subnets: []string{"subnet-0123456789abcdef", "subnet-fedcba9876543210"},
+				},
+				SubnetTags: map[string]string{"karpenter.sh/discovery": "my-cluster"},
+			},
+			expected: []awskarpenter.SubnetSelectorTerm{
+				{
+					Tags: map[string]string{"karpenter.sh/discovery": "my-cluster"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -545,6 +633,55 @@ func TestNodeGroup_SecurityGroupSelectorTerms(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Security groups of CustomLT take precedence over LT",
+			n: NodeGroup{
+				CustomLT: &ec2types.ResponseLaunchTemplateData{
+					SecurityGroupIds: []string{"sg-0123456789abcdef"},
+				},
+				LT: &ec2types.ResponseLaunchTemplateData{
+					SecurityGroupIds: []string{"sg-fedcba9876543210"},
+				},
+			},
+			expected: []awskarpenter.SecurityGroupSelectorTerm{
+				{
+					ID: "sg-0123456789abcdef",
+				},
+			},
+		},
+		{
+			name: "Security groups in LT generated by EKS",
+			n: NodeGroup{
+				LT: &ec2types.ResponseLaunchTemplateData{
+					SecurityGroupIds: []string{"sg-fedcba9876543210"},
+				},
+				ClusterSecurityGroupID: "sg-0c0c0c0c0c0c0c0c1",
+			},
+			expected: []awskarpenter.SecurityGroupSelectorTerm{
+				{
+					ID: "sg-fedcba9876543210",
+				},
+			},
+		},
+		{
+			name: "Cluster security group and remote access security group",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{
+					Resources: &ekstypes.NodegroupResources{
+						RemoteAccessSecurityGroup: lo.ToPtr("sg-0123456789abcdef"),
+					},
+				},
+				ClusterSecurityGroupID: "sg-0c0c0c0c0c0c0c0c1",
+			},
+			expected: []awskarpenter.SecurityGroupSelectorTerm{
+				{
+					ID: "sg-0c0c0c0c0c0c0c0c1",
+				},
+				{
+					ID: "sg-0123456789abcdef",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -617,6 +754,39 @@ func TestNodeGroup_BlockDeviceMappings(t *testing.T) {
 		n        NodeGroup
 		expected []*awskarpenter.BlockDeviceMapping
 	}{
+		{
+			name: "Block device mappings in LT generated by EKS",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{
+					AmiType:  ekstypes.AMITypesAl2X8664,
+					DiskSize: lo.ToPtr(int32(50)),
+				},
+				LT: &ec2types.ResponseLaunchTemplateData{
+					BlockDeviceMappings: []ec2types.LaunchTemplateBlockDeviceMapping{
+						{
+							DeviceName: lo.ToPtr("/dev/xvda"),
+							Ebs: &ec2types.LaunchTemplateEbsBlockDevice{
+								VolumeSize:          lo.ToPtr(int32(50)),
+								VolumeType:          ec2types.VolumeTypeGp2,
+								DeleteOnTermination: lo.ToPtr(true),
+							},
+						},
+					},
+				},
+			},
+			expected: []*awskarpenter.BlockDeviceMapping{
+				{
+					DeviceName: lo.ToPtr("/dev/xvda"),
+					EBS: &awskarpenter.BlockDevice{
+						VolumeSize:          k8sapiresource.NewQuantity(50*GiB, k8sapiresource.BinarySI),
+						VolumeType:          lo.ToPtr("gp2"),
+						DeleteOnTermination: lo.ToPtr(true),
+						// Not set in the LT, default of the AMI family
+						Encrypted: lo.ToPtr(true),
+					},
+				},
+			},
+		},
 		{
 			name: "Block device mappings in CustomLT",
 			n: NodeGroup{
@@ -751,6 +921,21 @@ func TestNodeGroup_MetadataOptions(t *testing.T) {
 			},
 			expected: nil,
 		},
+		{
+			name: "Metadata options in LT generated by EKS",
+			n: NodeGroup{
+				LT: &ec2types.ResponseLaunchTemplateData{
+					MetadataOptions: &ec2types.LaunchTemplateInstanceMetadataOptions{
+						HttpPutResponseHopLimit: lo.ToPtr(int32(2)),
+						HttpTokens:              ec2types.LaunchTemplateHttpTokensStateOptional,
+					},
+				},
+			},
+			expected: &awskarpenter.MetadataOptions{
+				HTTPPutResponseHopLimit: lo.ToPtr(int64(2)),
+				HTTPTokens:              lo.ToPtr("optional"),
+			},
+		},
 		{
 			name: "Nil CustomLT",
 			n: NodeGroup{
@@ -0,0 +1,89 @@
+package karpenteraws
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/punkwalker/karpenter-generate/pkg/instancetype"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+// SpotMinValues is the number of instance types Karpenter needs for spot-to-spot consolidation, minValues of
+// expanded Spot nodegroups is capped by it
+const SpotMinValues = 15
+
+// ExpandInstanceTypes replaces the instance types of the nodegroup by category, generation, CPU and memory
+// requirements covering them, instance types are kept when their capacity is unknown
+func (n *NodeGroup) ExpandInstanceTypes() {
+	if len(n.InstanceTypes) == 0 || len(n.InstanceRequirements) > 0 {
+		return
+	}
+
+	infos := []instancetype.Info{}
+	unknown := []string{}
+	for _, name := range n.InstanceTypes {
+		info, ok := n.instanceType(name)
+		if !ok || info.Category() == "" {
+			unknown = append(unknown, name)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	if len(unknown) > 0 {
+		warnings.Warnf(`nodegroup "%s": capacity of instance types %s is unknown, instance types are not expanded`, n.Name(), strings.Join(unknown, ","))
+		return
+	}
+
+	categories := lo.Uniq(lo.Map(infos, func(info instancetype.Info, _ int) string { return info.Category() }))
+	sort.Strings(categories)
+	generation := lo.Min(lo.Map(infos, func(info instancetype.Info, _ int) int { return info.Generation() }))
+	vcpus := lo.Map(infos, func(info instancetype.Info, _ int) int64 { return info.VCPU })
+	memory := lo.Map(infos, func(info instancetype.Info, _ int) int64 { return info.MemoryMiB })
+	minVCPU, maxVCPU := lo.Min(vcpus), lo.Max(vcpus)
+	minMemory, maxMemory := lo.Min(memory), lo.Max(memory)
+
+	reqs := []sigkarpenter.NodeSelectorRequirementWithMinValues{
+		requirement(awskarpenter.LabelInstanceCategory, corev1.NodeSelectorOpIn, categories...),
+		requirement(awskarpenter.LabelInstanceGeneration, corev1.NodeSelectorOpGt, strconv.Itoa(generation-1)),
+	}
+	reqs = append(reqs, rangeRequirements(awskarpenter.LabelInstanceCPU, minVCPU, maxVCPU)...)
+	reqs = append(reqs, rangeRequirements(awskarpenter.LabelInstanceMemory, minMemory, maxMemory)...)
+
+	// Spot needs instance types to choose from, the instance types of the nodegroup are known to match the requirements
+	// while the bundled catalog may list instance types which are not offered in the region
+	if lo.Contains(n.CapacityTypes(), "spot") {
+		req := requirement(corev1.LabelInstanceTypeStable, corev1.NodeSelectorOpExists)
+		req.MinValues = lo.ToPtr(min(len(n.InstanceTypes), SpotMinValues))
+		reqs = append(reqs, req)
+	}
+	n.InstanceRequirements = reqs
+}
+
+// Returns requirements of values between minimum and maximum, Karpenter intersects requirements of the same key
+func rangeRequirements(key string, minimum, maximum int64) []sigkarpenter.NodeSelectorRequirementWithMinValues {
+	if minimum == maximum {
+		return []sigkarpenter.NodeSelectorRequirementWithMinValues{
+			requirement(key, corev1.NodeSelectorOpIn, strconv.FormatInt(minimum, 10)),
+		}
+	}
+	return []sigkarpenter.NodeSelectorRequirementWithMinValues{
+		requirement(key, corev1.NodeSelectorOpGt, strconv.FormatInt(minimum-1, 10)),
+		requirement(key, corev1.NodeSelectorOpLt, strconv.FormatInt(maximum+1, 10)),
+	}
+}
+
+func requirement(key string, operator corev1.NodeSelectorOperator, values ...string) sigkarpenter.NodeSelectorRequirementWithMinValues {
+	return sigkarpenter.NodeSelectorRequirementWithMinValues{
+		NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+			Key:      key,
+			Operator: operator,
+			Values:   values,
+		},
+	}
+}
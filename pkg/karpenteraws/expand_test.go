@@ -0,0 +1,72 @@
+package karpenteraws
+
+import (
+	"reflect"
+	"testing"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func TestNodeGroup_ExpandInstanceTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        NodeGroup
+		expected []sigkarpenter.NodeSelectorRequirementWithMinValues
+	}{
+		{
+			name: "On-Demand nodegroup",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				AmiType:       ekstypes.AMITypesAl2X8664,
+				InstanceTypes: []string{"m5.xlarge", "m6i.2xlarge"},
+			}},
+			expected: []sigkarpenter.NodeSelectorRequirementWithMinValues{
+				requirement("karpenter.k8s.aws/instance-category", corev1.NodeSelectorOpIn, "m"),
+				requirement("karpenter.k8s.aws/instance-generation", corev1.NodeSelectorOpGt, "4"),
+				requirement("karpenter.k8s.aws/instance-cpu", corev1.NodeSelectorOpGt, "3"),
+				requirement("karpenter.k8s.aws/instance-cpu", corev1.NodeSelectorOpLt, "9"),
+				requirement("karpenter.k8s.aws/instance-memory", corev1.NodeSelectorOpGt, "16383"),
+				requirement("karpenter.k8s.aws/instance-memory", corev1.NodeSelectorOpLt, "32769"),
+			},
+		},
+		{
+			name: "Spot nodegroup",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				AmiType:       ekstypes.AMITypesAl2Arm64,
+				CapacityType:  ekstypes.CapacityTypesSpot,
+				InstanceTypes: []string{"c7g.large", "m7g.large"},
+			}},
+			expected: []sigkarpenter.NodeSelectorRequirementWithMinValues{
+				requirement("karpenter.k8s.aws/instance-category", corev1.NodeSelectorOpIn, "c", "m"),
+				requirement("karpenter.k8s.aws/instance-generation", corev1.NodeSelectorOpGt, "6"),
+				requirement("karpenter.k8s.aws/instance-cpu", corev1.NodeSelectorOpIn, "2"),
+				requirement("karpenter.k8s.aws/instance-memory", corev1.NodeSelectorOpGt, "4095"),
+				requirement("karpenter.k8s.aws/instance-memory", corev1.NodeSelectorOpLt, "8193"),
+				{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: "node.kubernetes.io/instance-type", Operator: corev1.NodeSelectorOpExists},
+					// c7g.large and m7g.large
+					MinValues: lo.ToPtr(2),
+				},
+			},
+		},
+		{
+			name: "Unknown instance type",
+			n: NodeGroup{Nodegroup: &ekstypes.Nodegroup{
+				NodegroupName: lo.ToPtr("ng"),
+				InstanceTypes: []string{"m5.large", "x2idn.16xlarge"},
+			}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.n.ExpandInstanceTypes()
+			if !reflect.DeepEqual(tt.n.InstanceRequirements, tt.expected) {
+				t.Errorf("NodeGroup.ExpandInstanceTypes() = %v, expected %v", tt.n.InstanceRequirements, tt.expected)
+			}
+		})
+	}
+}
@@ -1,45 +1,265 @@
 package karpenteraws
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
 	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/runtime"
 	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 
 	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/instancetype"
 	"github.com/punkwalker/karpenter-generate/pkg/options"
 )
 
+// NodegroupDescriber lists and describes EKS Managed Nodegroups,
+// DescribeNodegroup returns a nil nodegroup or an error when the nodegroup is not found
+type NodegroupDescriber interface {
+	ListNodegroups(ctx context.Context, clusterName string) ([]string, error)
+	DescribeNodegroup(ctx context.Context, clusterName, nodegroupName string) (*ekstypes.Nodegroup, error)
+}
+
+// ClusterDescriber describes EKS clusters, DescribeCluster returns a nil cluster or an error when the cluster is not found
+type ClusterDescriber interface {
+	DescribeCluster(ctx context.Context, clusterName string) (*ekstypes.Cluster, error)
+}
+
+// LaunchTemplateDescriber describes EC2 Launch Template versions,
+// DescribeLaunchTemplateVersions returns no versions or an error when the version is not found
+type LaunchTemplateDescriber interface {
+	DescribeLaunchTemplateVersions(ctx context.Context, id, version string) ([]ec2types.LaunchTemplateVersion, error)
+}
+
 type NodeGroup struct {
 	*ekstypes.Nodegroup
-	LT       *ec2types.ResponseLaunchTemplateData // LT Generated by MNG and used by ASG (needed for MetadataOptions)
+	LT       *ec2types.ResponseLaunchTemplateData // LT Generated by MNG and used by ASG (fallback for MetadataOptions, BlockDeviceMappings, tags and security groups)
 	CustomLT *ec2types.ResponseLaunchTemplateData // Custom LT provided to MNG
+	// Self-managed ASG the nodegroup is built from, nil for EKS Managed Nodegroups
+	AutoScalingGroup *autoscalingtypes.AutoScalingGroup
+	// Kubelet configuration of the NodePool template, nil when kubelet defaults are used
+	Kubelet *sigkarpenter.KubeletConfiguration
+	// Replaces the instance type requirement when instances are selected by attributes
+	InstanceRequirements []sigkarpenter.NodeSelectorRequirementWithMinValues
+	// Security group EKS created for the cluster, selected when no launch template has security groups
+	ClusterSecurityGroupID string
+	// AMIs of the release version of the managed nodegroup, empty to use the latest EKS optimized AMI
+	PinnedAMIs []string
+	// Architectures of the pinned AMIs, set when AMIs of the release version are not found for every architecture of the NodePool
+	PinnedArchitectures []string
+	// Instance types of the nodegroup described with the EC2 API, instance types of the bundled catalog are not described
+	InstanceTypeInfos map[string]instancetype.Info
+	// Disruption settings of the disruption policy, unset fields keep the generated disruption
+	DisruptionSettings DisruptionSettings
+	// Launch instances of both architectures when the AMI supports them
+	MultiArch bool
+	// Factor applied to the capacity of the nodegroup in NodePool limits, 0 is the same as 1
+	LimitsHeadroom float64
+	// Tags of subnets to select instead of subnet IDs
+	SubnetTags map[string]string
+	// Settings lifted out of the custom launch template user data, nil when user data is not parsed
+	UserDataSettings *UserDataSettings
+	// AMI family the nodegroup is moved to, empty to keep the AMI family of the nodegroup
+	TargetAMIFamily string
+}
+
+// Generator converts the nodegroups of the cluster of options with the AWS clients it holds
+type Generator struct {
+	Options       *options.Options
+	EKS           NodegroupDescriber
+	Cluster       ClusterDescriber
+	EC2           LaunchTemplateDescriber
+	Subnets       SubnetDescriber
+	InstanceTypes InstanceTypeDescriber
+	SSM           ParameterGetter
+	AutoScaling   AutoScalingGroupDescriber
+}
+
+// NewGenerator returns a Generator using live AWS clients or clients backed by saved AWS CLI output when input directory is set
+func NewGenerator(opts *options.Options) (*Generator, error) {
+	if opts.InputDir != "" {
+		fileClient, err := aws.NewFileClient(opts.InputDir)
+		if err != nil {
+			return nil, err
+		}
+		return &Generator{
+			Options:       opts,
+			EKS:           fileClient,
+			Cluster:       fileClient,
+			EC2:           fileClient,
+			Subnets:       fileClient,
+			InstanceTypes: fileClient,
+			SSM:           fileClient,
+			AutoScaling:   fileClient,
+		}, nil
+	}
+	return NewGeneratorFromClients(opts, aws.NewEKSClient(), aws.NewEC2Client(), aws.NewAutoScalingClient(), aws.NewSSMClient()), nil
+}
+
+// NewGeneratorFromAPIs returns a Generator calling the AWS APIs, e.g. the APIs of a fake backend in tests
+func NewGeneratorFromAPIs(opts *options.Options, eksAPI aws.EKSAPI, ec2API aws.EC2API, asgAPI aws.AutoScalingAPI, ssmAPI aws.SSMAPI) *Generator {
+	return NewGeneratorFromClients(opts, aws.NewEKSClientFromAPI(eksAPI), aws.NewEC2ClientFromAPI(ec2API),
+		aws.NewAutoScalingClientFromAPI(asgAPI), aws.NewSSMClientFromAPI(ssmAPI))
+}
+
+// NewGeneratorFromClients returns a Generator using the AWS clients
+func NewGeneratorFromClients(opts *options.Options, eksClient *aws.EKSClient, ec2Client *aws.EC2Client, asgClient *aws.AutoScalingClient, ssmClient *aws.SSMClient) *Generator {
+	return &Generator{
+		Options:       opts,
+		EKS:           eksClient,
+		Cluster:       eksClient,
+		EC2:           ec2Client,
+		Subnets:       ec2Client,
+		InstanceTypes: ec2Client,
+		SSM:           ssmClient,
+		AutoScaling:   asgClient,
+	}
+}
+
+// Generate returns NodePools and EC2NodeClasses for the API version requested in options
+func Generate(ctx context.Context, opts *options.Options) ([]runtime.Object, error) {
+	generator, err := NewGenerator(opts)
+	if err != nil {
+		return nil, err
+	}
+	return generator.Generate(ctx)
+}
+
+// Generate returns NodePools and EC2NodeClasses of the nodegroups of the cluster
+func (g *Generator) Generate(ctx context.Context) ([]runtime.Object, error) {
+	nodeGroups, err := g.NodeGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateFromNodeGroups(g.Options, nodeGroups)
+}
+
+// GenerateFromNodeGroups merges similar nodegroups and returns NodePools and EC2NodeClasses for the API version requested in options
+func GenerateFromNodeGroups(opts *options.Options, nodeGroups []*NodeGroup) ([]runtime.Object, error) {
+	policy, err := LoadDisruptionPolicy(opts)
+	if err != nil {
+		return nil, err
+	}
+	policy.apply(nodeGroups)
+	for _, nodegroup := range nodeGroups {
+		if err := nodegroup.ValidateArchitectures(); err != nil {
+			return nil, err
+		}
+		nodegroup.LimitsHeadroom = opts.LimitsHeadroom
+		nodegroup.MultiArch = opts.MultiArch
+		if opts.ExpandInstanceTypes {
+			nodegroup.ExpandInstanceTypes()
+		}
+		if opts.MultiArch {
+			nodegroup.warnMultiArch()
+		}
+	}
+	nodePools, nodeClasses, err := generateV1beta1(nodeGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.APIVersion {
+	case options.APIVersionV1:
+		return toV1(nodePools, nodeClasses)
+	default:
+		if err := validateDisruptionV1beta1(nodePools); err != nil {
+			return nil, err
+		}
+		return toV1beta1(nodePools, nodeClasses), nil
+	}
 }
 
-func Generate(opts *options.Options) ([]sigkarpenter.NodePool, []awskarpenter.EC2NodeClass, error) {
-	nodeGroups, err := getNodegroups(opts)
+// NodeGroups returns EKS Managed Nodegroups and self-managed Auto Scaling groups of the cluster
+func (g *Generator) NodeGroups(ctx context.Context) ([]*NodeGroup, error) {
+	opts := g.Options
+	ngs, err := getNodegroups(ctx, opts, g.EKS)
 	if err != nil {
-		return nil, nil, aws.FormatErrorAsMessageOnly(err)
+		return nil, aws.FormatErrorAsMessageOnly(err)
 	}
 
-	if len(nodeGroups) == 0 {
-		return nil, nil, fmt.Errorf("no nodegroups found")
+	asgs, err := getAutoScalingGroups(ctx, opts, g.AutoScaling)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ngs) == 0 && len(asgs) == 0 {
+		return nil, fmt.Errorf("no nodegroups found")
+	}
+
+	cluster, err := g.Cluster.DescribeCluster(ctx, opts.ClusterName)
+	if err != nil {
+		return nil, aws.FormatErrorAsMessageOnly(err)
 	}
 
+	ClusterTag = map[string]string{
+		ClusterTagKey + opts.ClusterName: "owned",
+	}
+
+	// Nodegroups are described concurrently and keep the order of the nodegroup list
+	ec2Client := newLaunchTemplateCache(g.EC2)
+	nodeGroups := make([]*NodeGroup, len(ngs)+len(asgs))
+	err = forEachIndex(ctx, opts.Concurrency, len(nodeGroups), func(idx int) error {
+		if idx >= len(ngs) {
+			nodegroup, err := NewAutoScalingNodeGroup(ctx, opts.ClusterName, &asgs[idx-len(ngs)], ec2Client)
+			nodeGroups[idx] = nodegroup
+			return err
+		}
+		nodegroup, err := NewNodeGroup(ctx, ngs[idx], ec2Client)
+		if err != nil {
+			return err
+		}
+		nodeGroups[idx] = nodegroup
+		return nodegroup.ResolveLaunchTemplate(ctx, g.AutoScaling, ec2Client)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveInstanceTypes(ctx, nodeGroups, g.InstanceTypes); err != nil {
+		return nil, err
+	}
+
+	for _, nodegroup := range nodeGroups {
+		if cluster != nil && cluster.ResourcesVpcConfig != nil {
+			nodegroup.ClusterSecurityGroupID = lo.FromPtr(cluster.ResourcesVpcConfig.ClusterSecurityGroupId)
+		}
+		if opts.SubnetTags {
+			if err := nodegroup.SelectSubnetsByTags(ctx, g.Subnets); err != nil {
+				return nil, err
+			}
+		}
+		if err := nodegroup.MigrateUserData(opts.TargetAMIFamily); err != nil {
+			return nil, err
+		}
+		if opts.PinAMI {
+			// AMIs of both architectures are pinned with multi-arch
+			nodegroup.MultiArch = opts.MultiArch
+			if err := nodegroup.PinAMI(ctx, g.SSM); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nodeGroups, nil
+}
+
+func generateV1beta1(nodeGroups []*NodeGroup) ([]sigkarpenter.NodePool, []awskarpenter.EC2NodeClass, error) {
 	npMap := map[string]*sigkarpenter.NodePool{}
 	ncMap := map[string]*awskarpenter.EC2NodeClass{}
 	mergedNcMap := map[string]string{}
 
-	for _, ng := range nodeGroups {
-		nodegroup, err := NewNodeGroup(ng)
-		if err != nil {
-			return nil, nil, err
-		}
+	// Similar nodegroups are merged into the NodePool and EC2NodeClass of the lexicographically first nodegroup
+	nodeGroups = append([]*NodeGroup{}, nodeGroups...)
+	sort.SliceStable(nodeGroups, func(i, j int) bool { return nodeGroups[i].Name() < nodeGroups[j].Name() })
 
+	for _, nodegroup := range nodeGroups {
 		ec2Class, err := nodegroup.GetEC2NodeClass()
 		if err != nil {
 			return nil, nil, err
@@ -59,66 +279,80 @@ func Generate(opts *options.Options) ([]sigkarpenter.NodePool, []awskarpenter.EC
 	nodePools := lo.MapToSlice(npMap, func(_ string, v *sigkarpenter.NodePool) sigkarpenter.NodePool {
 		return *v
 	})
+	sort.Slice(nodePools, func(i, j int) bool { return nodePools[i].Name < nodePools[j].Name })
 
 	nodeClasses := lo.MapToSlice(ncMap, func(_ string, v *awskarpenter.EC2NodeClass) awskarpenter.EC2NodeClass {
 		return *v
 	})
+	sort.Slice(nodeClasses, func(i, j int) bool { return nodeClasses[i].Name < nodeClasses[j].Name })
 	return nodePools, nodeClasses, nil
 }
 
-func NewNodeGroup(ng ekstypes.Nodegroup) (*NodeGroup, error) {
+func toV1beta1(nodePools []sigkarpenter.NodePool, nodeClasses []awskarpenter.EC2NodeClass) []runtime.Object {
+	objs := []runtime.Object{}
+	for idx := range nodePools {
+		objs = append(objs, &nodePools[idx])
+	}
+	for idx := range nodeClasses {
+		objs = append(objs, &nodeClasses[idx])
+	}
+	return objs
+}
+
+func NewNodeGroup(ctx context.Context, ng ekstypes.Nodegroup, ec2Client LaunchTemplateDescriber) (*NodeGroup, error) {
 
 	newNodegroup := NodeGroup{
 		Nodegroup: &ng,
 	}
 
-	ClusterTag = map[string]string{
-		ClusterTagKey + *ng.ClusterName: "owned",
-	}
-
-	ec2Client := aws.NewEC2Client()
-
 	if ng.LaunchTemplate != nil {
-		customLT, err := ec2Client.DescribeLaunchTemplateVersions(
+		customLT, err := ec2Client.DescribeLaunchTemplateVersions(ctx,
 			*ng.LaunchTemplate.Id,
 			*ng.LaunchTemplate.Version)
 		if err != nil {
 			return nil, aws.FormatErrorAsMessageOnly(err)
 		}
+		if len(customLT) == 0 {
+			return nil, fmt.Errorf(`nodegroup "%s": version "%s" of launch template "%s" not found`,
+				lo.FromPtr(ng.NodegroupName), *ng.LaunchTemplate.Version, *ng.LaunchTemplate.Id)
+		}
 		newNodegroup.CustomLT = customLT[0].LaunchTemplateData
 	}
 
 	return &newNodegroup, nil
 }
 
-func getNodegroups(opts *options.Options) ([]ekstypes.Nodegroup, error) {
-	var nodegroups []ekstypes.Nodegroup
+func getNodegroups(ctx context.Context, opts *options.Options, eksClient NodegroupDescriber) ([]ekstypes.Nodegroup, error) {
 	var ngList []string
 	var err error
 
-	eksClient := aws.NewEKSClient()
-
 	if opts.NodegroupName != "" {
 		ngList = []string{opts.NodegroupName}
 	} else {
-		ngList, err = eksClient.ListNodegroups(opts.ClusterName)
+		ngList, err = eksClient.ListNodegroups(ctx, opts.ClusterName)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	for _, ng := range ngList {
-		if opts.KarpenterNodegroupName != ng {
-			nodegroup, err := eksClient.DescribeNodegroup(opts.ClusterName, ng)
-			if err != nil {
-				return nil, err
-			}
-
-			if nodegroup.Status != ekstypes.NodegroupStatusActive {
-				return nil, fmt.Errorf(`nodegroup "%s" is not active, make sure all the nodegroups are in "ACTIVE" state`, ng)
-			}
-			nodegroups = append(nodegroups, *nodegroup)
+	ngList = lo.Without(ngList, opts.KarpenterNodegroupName)
+	nodegroups := make([]ekstypes.Nodegroup, len(ngList))
+	err = forEachIndex(ctx, opts.Concurrency, len(ngList), func(idx int) error {
+		nodegroup, err := eksClient.DescribeNodegroup(ctx, opts.ClusterName, ngList[idx])
+		if err != nil {
+			return err
 		}
+		if nodegroup == nil {
+			return fmt.Errorf(`nodegroup "%s" not found`, ngList[idx])
+		}
+		if nodegroup.Status != ekstypes.NodegroupStatusActive {
+			return fmt.Errorf(`nodegroup "%s" is not active, make sure all the nodegroups are in "ACTIVE" state`, ngList[idx])
+		}
+		nodegroups[idx] = *nodegroup
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return nodegroups, nil
 }
@@ -138,7 +372,9 @@ func mergeNP(newNP sigkarpenter.NodePool, npMap map[string]*sigkarpenter.NodePoo
 		modifiedNP.Spec.Template.Spec.NodeClassRef.Name = val
 	}
 
-	npHash := modifiedNP.Hash()
+	// NodePools with different disruption are not merged, the hash only covers the template
+	disruption, _ := json.Marshal(modifiedNP.Spec.Disruption)
+	npHash := modifiedNP.Hash() + string(disruption)
 	// Add Nodepool to map if nodepool does not exists
 	if np, exists := (npMap)[npHash]; !exists {
 		(npMap)[npHash] = &newNP
@@ -150,6 +386,8 @@ func mergeNP(newNP sigkarpenter.NodePool, npMap map[string]*sigkarpenter.NodePoo
 			np.Annotations["migrate.karpenter.sh/merged-nodepools"] = fmt.Sprintf("%s,%s", val, modifiedNP.Annotations["migrate.karpenter.sh/source-nodegroup"])
 		}
 
+		np.Spec.Limits = sumLimits(np.Spec.Limits, newNP.Spec.Limits)
+
 		// Append Instance Types to existing Nodepool Instance Types
 		for idx, req := range np.Spec.Template.Spec.Requirements {
 			if req.Key == "node.kubernetes.io/instance-type" {
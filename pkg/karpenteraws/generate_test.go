@@ -0,0 +1,112 @@
+package karpenteraws
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/printers"
+
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// Each directory in testdata/golden holds saved AWS CLI output of a cluster and
+// the expected output for every API version as <api-version>.yaml
+func TestGenerate_Golden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range dirs {
+		for _, apiVersion := range []string{options.APIVersionV1beta1, options.APIVersionV1} {
+			t.Run(filepath.Base(dir)+"/"+apiVersion, func(t *testing.T) {
+				opts := &options.Options{
+					ClusterName:            "my-cluster",
+					KarpenterNodegroupName: "karpenter",
+					APIVersion:             apiVersion,
+					InputDir:               dir,
+					AutoScalingGroupTag:    "kubernetes.io/cluster/my-cluster=owned",
+				}
+
+				objs, err := Generate(context.Background(), opts)
+				if err != nil {
+					t.Fatalf("Generate() error = %v", err)
+				}
+
+				got := &bytes.Buffer{}
+				printer := &printers.YAMLPrinter{}
+				for _, obj := range objs {
+					if err := printer.PrintObj(obj, got); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				goldenFile := filepath.Join(dir, apiVersion+".yaml")
+				if *update {
+					if err := os.WriteFile(goldenFile, got.Bytes(), 0o600); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				expected, err := os.ReadFile(goldenFile) // #nosec G304
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got.Bytes(), expected) {
+					t.Errorf("Generate() output does not match %s, got:\n%s", goldenFile, got.String())
+				}
+			})
+		}
+	}
+}
+
+func TestNewNodeGroup(t *testing.T) {
+	launchTemplates := fakeLaunchTemplateDescriber{
+		"lt-1": {{LaunchTemplateId: lo.ToPtr("lt-1"), LaunchTemplateData: &ec2types.ResponseLaunchTemplateData{ImageId: lo.ToPtr("ami-1")}}},
+	}
+	tests := []struct {
+		name             string
+		launchTemplate   *ekstypes.LaunchTemplateSpecification
+		expectedCustomLT *ec2types.ResponseLaunchTemplateData
+		err              string
+	}{
+		{
+			name: "Without launch template",
+		},
+		{
+			name:             "Custom launch template",
+			launchTemplate:   &ekstypes.LaunchTemplateSpecification{Id: lo.ToPtr("lt-1"), Version: lo.ToPtr("1")},
+			expectedCustomLT: &ec2types.ResponseLaunchTemplateData{ImageId: lo.ToPtr("ami-1")},
+		},
+		{
+			name:           "Launch template version not found",
+			launchTemplate: &ekstypes.LaunchTemplateSpecification{Id: lo.ToPtr("lt-missing"), Version: lo.ToPtr("2")},
+			err:            `nodegroup "ng": version "2" of launch template "lt-missing" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ng := ekstypes.Nodegroup{NodegroupName: lo.ToPtr("ng"), LaunchTemplate: tt.launchTemplate}
+
+			nodeGroup, err := NewNodeGroup(context.Background(), ng, launchTemplates)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCustomLT, nodeGroup.CustomLT)
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package karpenteraws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/printers"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	karpenterv1 "github.com/punkwalker/karpenter-generate/pkg/apis/v1"
+	"github.com/punkwalker/karpenter-generate/pkg/aws/fake"
+	"github.com/punkwalker/karpenter-generate/pkg/options"
+)
+
+// Returns the source nodegroups of each NodePool of any API version, merged nodegroups are joined with the source nodegroup
+func nodePoolSources(objs []runtime.Object) []string {
+	sources := lo.FilterMap(objs, func(obj runtime.Object, _ int) (string, bool) {
+		var annotations map[string]string
+		switch np := obj.(type) {
+		case *sigkarpenter.NodePool:
+			annotations = np.Annotations
+		case *karpenterv1.NodePool:
+			annotations = np.Annotations
+		default:
+			return "", false
+		}
+		names := []string{annotations["migrate.karpenter.sh/source-nodegroup"]}
+		if merged, ok := annotations["migrate.karpenter.sh/merged-nodepools"]; ok {
+			names = append(names, strings.Split(merged, ",")...)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ","), true
+	})
+	sort.Strings(sources)
+	return sources
+}
+
+// Whole-cluster scenarios served by the fake backend from the fixtures in testdata/scenarios
+func TestGenerator_Scenarios(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario string
+		setup    func(b *fake.Backend)
+		// Source nodegroups of the NodePools, ignored when an error is expected
+		expected []string
+		err      string
+	}{
+		{
+			name:     "Similar nodegroups are merged",
+			scenario: "merge",
+			expected: []string{"batch", "web-a,web-b"},
+		},
+		{
+			name:     "Nodegroups listed in pages",
+			scenario: "merge",
+			setup:    func(b *fake.Backend) { b.PageSize = 1 },
+			expected: []string{"batch", "web-a,web-b"},
+		},
+		{
+			name:     "Inactive nodegroup",
+			scenario: "merge",
+			setup: func(b *fake.Backend) {
+				b.Nodegroups[0].Status = ekstypes.NodegroupStatusDegraded
+			},
+			err: `nodegroup "batch" is not active`,
+		},
+		{
+			name:     "API error",
+			scenario: "merge",
+			setup: func(b *fake.Backend) {
+				b.Errors["DescribeNodegroup"] = &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform eks:DescribeNodegroup"}
+			},
+			err: "not authorized to perform eks:DescribeNodegroup",
+		},
+		{
+			name:     "Cluster not found",
+			scenario: "merge",
+			setup:    func(b *fake.Backend) { b.Clusters = nil },
+			err:      "No cluster found for name: my-cluster.",
+		},
+		{
+			name:     "Empty cluster response",
+			scenario: "merge",
+			setup:    func(b *fake.Backend) { b.Empty["DescribeCluster"] = true },
+			expected: []string{"batch", "web-a,web-b"},
+		},
+		{
+			name:     "Empty nodegroup response",
+			scenario: "merge",
+			setup:    func(b *fake.Backend) { b.Empty["DescribeNodegroup"] = true },
+			err:      `nodegroup "batch" not found`,
+		},
+		{
+			name:     "Empty launch template versions response",
+			scenario: "merge",
+			setup:    func(b *fake.Backend) { b.Empty["DescribeLaunchTemplateVersions"] = true },
+			err:      `version "1" of launch template "lt-0f0f0f0f0f0f0f0f1" not found`,
+		},
+		{
+			name:     "Nodegroup without role",
+			scenario: "merge",
+			setup:    func(b *fake.Backend) { b.Nodegroups[0].NodeRole = nil },
+			err:      "expected exactly one, got neither: spec.instanceProfile, spec.role",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, apiVersion := range []string{options.APIVersionV1beta1, options.APIVersionV1} {
+			t.Run(tt.name+"/"+apiVersion, func(t *testing.T) {
+				backend, err := fake.NewBackend(filepath.Join("testdata", "scenarios", tt.scenario))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.setup != nil {
+					tt.setup(backend)
+				}
+				opts := &options.Options{ClusterName: "my-cluster", KarpenterNodegroupName: "karpenter", APIVersion: apiVersion}
+
+				objs, err := NewGeneratorFromAPIs(opts, backend, backend, backend, backend).Generate(context.Background())
+				if tt.err != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.err) {
+						t.Fatalf("Generator.Generate() error = %v, expected %s", err, tt.err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Generator.Generate() error = %v", err)
+				}
+				if got := nodePoolSources(objs); !reflect.DeepEqual(got, tt.expected) {
+					t.Errorf("Generator.Generate() NodePools of %v, expected %v", got, tt.expected)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerator_Pagination(t *testing.T) {
+	backend, err := fake.NewBackend(filepath.Join("testdata", "scenarios", "merge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.PageSize = 1
+	opts := &options.Options{ClusterName: "my-cluster", KarpenterNodegroupName: "karpenter"}
+
+	nodeGroups, err := NewGeneratorFromAPIs(opts, backend, backend, backend, backend).NodeGroups(context.Background())
+	if err != nil {
+		t.Fatalf("Generator.NodeGroups() error = %v", err)
+	}
+	if len(nodeGroups) != 3 {
+		t.Errorf("Generator.NodeGroups() returned %d nodegroups, expected 3", len(nodeGroups))
+	}
+	if calls := backend.Calls("ListNodegroups"); calls != 4 {
+		t.Errorf("ListNodegroups called %d times, expected a call per nodegroup", calls)
+	}
+	if calls := backend.Calls("DescribeNodegroup"); calls != 3 {
+		t.Errorf("DescribeNodegroup called %d times, the Karpenter nodegroup is not described", calls)
+	}
+}
+
+func TestGenerator_Cancelled(t *testing.T) {
+	backend, err := fake.NewBackend(filepath.Join("testdata", "scenarios", "merge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &options.Options{ClusterName: "my-cluster", KarpenterNodegroupName: "karpenter"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewGeneratorFromAPIs(opts, backend, backend, backend, backend).Generate(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Generator.Generate() error = %v, expected %v", err, context.Canceled)
+	}
+	if calls := backend.Calls("DescribeNodegroup"); calls != 0 {
+		t.Errorf("DescribeNodegroup called %d times, no calls are started once the context is done", calls)
+	}
+}
+
+func TestGenerator_Concurrency(t *testing.T) {
+	for _, concurrency := range []int{1, 8} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			backend, err := fake.NewBackend(filepath.Join("testdata", "scenarios", "merge"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := &options.Options{ClusterName: "my-cluster", KarpenterNodegroupName: "karpenter", Concurrency: concurrency}
+
+			objs, err := NewGeneratorFromAPIs(opts, backend, backend, backend, backend).Generate(context.Background())
+			if err != nil {
+				t.Fatalf("Generator.Generate() error = %v", err)
+			}
+			if got, expected := nodePoolSources(objs), []string{"batch", "web-a,web-b"}; !reflect.DeepEqual(got, expected) {
+				t.Errorf("Generator.Generate() NodePools of %v, expected %v", got, expected)
+			}
+			if calls := backend.Calls("DescribeLaunchTemplateVersions"); calls != 1 {
+				t.Errorf("DescribeLaunchTemplateVersions called %d times, the launch template of web-a and web-b is described once", calls)
+			}
+
+			// The error of the first nodegroup is returned whatever the completion order
+			backend.Nodegroups[0].Status = ekstypes.NodegroupStatusDegraded
+			backend.Nodegroups[3].Status = ekstypes.NodegroupStatusDegraded
+			_, err = NewGeneratorFromAPIs(opts, backend, backend, backend, backend).Generate(context.Background())
+			if err == nil || !strings.Contains(err.Error(), `nodegroup "batch" is not active`) {
+				t.Errorf("Generator.Generate() error = %v, expected error of nodegroup batch", err)
+			}
+		})
+	}
+}
+
+// Output is byte-identical whatever the order of the nodegroups and the order in which they are described
+func TestGenerator_DeterministicOutput(t *testing.T) {
+	var expected []byte
+	for seed := int64(0); seed < 10; seed++ {
+		backend, err := fake.NewBackend(filepath.Join("testdata", "scenarios", "merge"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rand.New(rand.NewSource(seed)).Shuffle(len(backend.Nodegroups), func(i, j int) {
+			backend.Nodegroups[i], backend.Nodegroups[j] = backend.Nodegroups[j], backend.Nodegroups[i]
+		})
+		opts := &options.Options{ClusterName: "my-cluster", KarpenterNodegroupName: "karpenter", Concurrency: 8}
+
+		objs, err := NewGeneratorFromAPIs(opts, backend, backend, backend, backend).Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generator.Generate() error = %v", err)
+		}
+		got := &bytes.Buffer{}
+		printer := &printers.YAMLPrinter{}
+		for _, obj := range objs {
+			if err := printer.PrintObj(obj, got); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if expected == nil {
+			expected = got.Bytes()
+			names := lo.Map(objs, func(obj runtime.Object, _ int) string {
+				return obj.GetObjectKind().GroupVersionKind().Kind + "/" + obj.(metav1.Object).GetName()
+			})
+			// Merged resources keep the name of the lexicographically first nodegroup
+			if want := []string{"NodePool/batch", "NodePool/web-a", "EC2NodeClass/batch", "EC2NodeClass/web-a"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("Generator.Generate() returned %v, expected %v", names, want)
+			}
+			continue
+		}
+		if !bytes.Equal(got.Bytes(), expected) {
+			t.Errorf("Generator.Generate() output with seed %d differs from seed 0:\n%s", seed, got.String())
+		}
+	}
+}
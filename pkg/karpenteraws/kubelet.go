@@ -0,0 +1,113 @@
+package karpenteraws
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+// Returns the Karpenter kubelet configuration of KubeletConfiguration (camelCase) keys and
+// the keys Karpenter does not support, nil is returned if no key is supported
+func KubeletFromConfig(config map[string]any) (*sigkarpenter.KubeletConfiguration, []string, error) {
+	supported := map[string]any{}
+	unsupported := []string{}
+	for key, val := range config {
+		data, err := json.Marshal(map[string]any{key: val})
+		if err != nil {
+			return nil, nil, err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&sigkarpenter.KubeletConfiguration{}); err != nil {
+			unsupported = append(unsupported, key)
+			continue
+		}
+		supported[key] = val
+	}
+	sort.Strings(unsupported)
+
+	data, err := json.Marshal(supported)
+	if err != nil {
+		return nil, nil, err
+	}
+	kubelet := &sigkarpenter.KubeletConfiguration{}
+	if err := json.Unmarshal(data, kubelet); err != nil {
+		return nil, nil, err
+	}
+	if equality.Semantic.DeepEqual(*kubelet, sigkarpenter.KubeletConfiguration{}) {
+		return nil, unsupported, nil
+	}
+	return kubelet, unsupported, nil
+}
+
+// kubeletArgs holds kubelet command line flags translated into KubeletConfiguration keys, labels and taints,
+// flags Karpenter does not support are kept with their values
+type kubeletArgs struct {
+	config      map[string]any
+	labels      map[string]string
+	taints      []corev1.Taint
+	unsupported []string
+}
+
+// Parses kubelet command line flags (e.g.: --max-pods=110 --node-labels=a=b)
+func parseKubeletArgs(args []string, parsed *kubeletArgs) {
+	for idx := 0; idx < len(args); idx++ {
+		flag, val, hasVal := strings.Cut(strings.TrimLeft(args[idx], "-"), "=")
+		if !hasVal && idx+1 < len(args) && !strings.HasPrefix(args[idx+1], "-") {
+			idx++
+			val = args[idx]
+		}
+
+		switch flag {
+		case "max-pods", "pods-per-core":
+			num, err := strconv.Atoi(val)
+			if err != nil {
+				parsed.unsupported = append(parsed.unsupported, "--"+flag+"="+val)
+				continue
+			}
+			parsed.config[lo.Ternary(flag == "max-pods", "maxPods", "podsPerCore")] = num
+		case "node-labels":
+			for key, v := range splitKeyValues(val, "=") {
+				parsed.labels[key] = v
+			}
+		case "register-with-taints":
+			for _, taint := range strings.Split(val, ",") {
+				keyVal, effect, _ := strings.Cut(taint, ":")
+				key, value, _ := strings.Cut(keyVal, "=")
+				if key != "" {
+					parsed.taints = append(parsed.taints, corev1.Taint{Key: key, Value: value, Effect: corev1.TaintEffect(effect)})
+				}
+			}
+		case "system-reserved":
+			parsed.config["systemReserved"] = splitKeyValues(val, "=")
+		case "kube-reserved":
+			parsed.config["kubeReserved"] = splitKeyValues(val, "=")
+		case "eviction-hard":
+			parsed.config["evictionHard"] = splitKeyValues(val, "<")
+		case "eviction-soft":
+			parsed.config["evictionSoft"] = splitKeyValues(val, "<")
+		case "cluster-dns":
+			parsed.config["clusterDNS"] = strings.Split(val, ",")
+		default:
+			parsed.unsupported = append(parsed.unsupported, "--"+flag+lo.Ternary(val != "", "="+val, ""))
+		}
+	}
+}
+
+// Splits comma separated pairs (e.g.: cpu=100m,memory=100Mi)
+func splitKeyValues(val, sep string) map[string]string {
+	pairs := map[string]string{}
+	for _, pair := range strings.Split(val, ",") {
+		if key, v, ok := strings.Cut(pair, sep); ok {
+			pairs[strings.TrimSpace(key)] = strings.TrimSpace(v)
+		}
+	}
+	return pairs
+}
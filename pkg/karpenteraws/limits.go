@@ -0,0 +1,112 @@
+package karpenteraws
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/instancetype"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+const DefaultCPULimit string = "1000"
+
+// InstanceTypeDescriber describes EC2 instance types, instance types which are not found are left out of the result
+type InstanceTypeDescriber interface {
+	DescribeInstanceTypes(ctx context.Context, names []string) ([]ec2types.InstanceTypeInfo, error)
+}
+
+// Describes instance types of the nodegroups which are not in the bundled catalog with a single call
+func resolveInstanceTypes(ctx context.Context, nodeGroups []*NodeGroup, client InstanceTypeDescriber) error {
+	names := lo.Uniq(lo.FlatMap(nodeGroups, func(n *NodeGroup, _ int) []string {
+		return lo.Filter(n.InstanceTypes, func(name string, _ int) bool {
+			_, ok := instancetype.Lookup(name)
+			return !ok
+		})
+	}))
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	described, err := client.DescribeInstanceTypes(ctx, names)
+	if err != nil {
+		return aws.FormatErrorAsMessageOnly(err)
+	}
+	infos := lo.SliceToMap(described, func(info ec2types.InstanceTypeInfo) (string, instancetype.Info) {
+		return string(info.InstanceType), instancetype.FromEC2(info)
+	})
+	for _, n := range nodeGroups {
+		n.InstanceTypeInfos = infos
+	}
+	return nil
+}
+
+// Returns the capacity of the instance type from the bundled catalog or the EC2 API
+func (n NodeGroup) instanceType(name string) (instancetype.Info, bool) {
+	if info, ok := instancetype.Lookup(name); ok {
+		return info, true
+	}
+	info, ok := n.InstanceTypeInfos[name]
+	return info, ok
+}
+
+// Returns CPU and memory of MaxSize nodes of the largest instance types times the headroom,
+// the default CPU limit is used when the size or the instance types of the nodegroup are unknown
+func (n NodeGroup) Limits() sigkarpenter.Limits {
+	defaultLimits := sigkarpenter.Limits{
+		corev1.ResourceCPU: resource.MustParse(DefaultCPULimit),
+	}
+	if n.ScalingConfig == nil || lo.FromPtr(n.ScalingConfig.MaxSize) == 0 || len(n.InstanceTypes) == 0 {
+		return defaultLimits
+	}
+
+	var vcpu, memoryMiB int64
+	unknown := []string{}
+	for _, name := range n.InstanceTypes {
+		info, ok := n.instanceType(name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		vcpu = max(vcpu, info.VCPU)
+		memoryMiB = max(memoryMiB, info.MemoryMiB)
+	}
+	if len(unknown) > 0 {
+		warnings.Warnf(`nodegroup "%s": capacity of instance types %s is unknown, NodePool limits are set to "cpu: %s"`, n.Name(), strings.Join(unknown, ","), DefaultCPULimit)
+		return defaultLimits
+	}
+
+	headroom := n.LimitsHeadroom
+	if headroom == 0 {
+		headroom = 1
+	}
+	maxSize := float64(*n.ScalingConfig.MaxSize) * headroom
+	return sigkarpenter.Limits{
+		corev1.ResourceCPU:    *resource.NewQuantity(int64(math.Ceil(float64(vcpu)*maxSize)), resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(int64(math.Ceil(float64(memoryMiB)*maxSize))*1024*1024, resource.BinarySI),
+	}
+}
+
+// Sums limits of merged NodePools, resources which are not limited in both NodePools are not limited
+func sumLimits(a, b sigkarpenter.Limits) sigkarpenter.Limits {
+	sum := sigkarpenter.Limits{}
+	for name, quantity := range a {
+		other, ok := b[name]
+		if !ok {
+			continue
+		}
+		quantity = quantity.DeepCopy()
+		quantity.Add(other)
+		sum[name] = quantity
+	}
+	return sum
+}
@@ -0,0 +1,124 @@
+package karpenteraws
+
+import (
+	"context"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	"github.com/punkwalker/karpenter-generate/pkg/instancetype"
+)
+
+type fakeInstanceTypeDescriber []ec2types.InstanceTypeInfo
+
+func (f fakeInstanceTypeDescriber) DescribeInstanceTypes(_ context.Context, names []string) ([]ec2types.InstanceTypeInfo, error) {
+	return lo.Filter(f, func(info ec2types.InstanceTypeInfo, _ int) bool {
+		return lo.Contains(names, string(info.InstanceType))
+	}), nil
+}
+
+func TestNodeGroup_Limits(t *testing.T) {
+	nodegroup := func(maxSize int32, instanceTypes ...string) *ekstypes.Nodegroup {
+		return &ekstypes.Nodegroup{
+			NodegroupName: lo.ToPtr("ng"),
+			InstanceTypes: instanceTypes,
+			ScalingConfig: &ekstypes.NodegroupScalingConfig{MaxSize: lo.ToPtr(maxSize)},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		n        NodeGroup
+		expected sigkarpenter.Limits
+	}{
+		{
+			name: "Largest instance type",
+			n:    NodeGroup{Nodegroup: nodegroup(3, "m5.large", "r5.large", "c5.xlarge")},
+			expected: sigkarpenter.Limits{
+				corev1.ResourceCPU:    resource.MustParse("12"),
+				corev1.ResourceMemory: resource.MustParse("48Gi"),
+			},
+		},
+		{
+			name: "Headroom",
+			n:    NodeGroup{Nodegroup: nodegroup(3, "m5.large"), LimitsHeadroom: 1.5},
+			expected: sigkarpenter.Limits{
+				corev1.ResourceCPU:    resource.MustParse("9"),
+				corev1.ResourceMemory: resource.MustParse("36Gi"),
+			},
+		},
+		{
+			name: "Instance type described with the EC2 API",
+			n: NodeGroup{
+				Nodegroup: nodegroup(2, "x2gd.large"),
+				InstanceTypeInfos: map[string]instancetype.Info{
+					"x2gd.large": {Name: "x2gd.large", VCPU: 2, MemoryMiB: 32768},
+				},
+			},
+			expected: sigkarpenter.Limits{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("64Gi"),
+			},
+		},
+		{
+			name:     "Unknown instance type",
+			n:        NodeGroup{Nodegroup: nodegroup(3, "m5.large", "x2gd.large")},
+			expected: sigkarpenter.Limits{corev1.ResourceCPU: resource.MustParse(DefaultCPULimit)},
+		},
+		{
+			name:     "Unknown size",
+			n:        NodeGroup{Nodegroup: &ekstypes.Nodegroup{InstanceTypes: []string{"m5.large"}}},
+			expected: sigkarpenter.Limits{corev1.ResourceCPU: resource.MustParse(DefaultCPULimit)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.n.Limits()
+			assert.Equal(t, len(tt.expected), len(got))
+			for name, quantity := range tt.expected {
+				actual := got[name]
+				assert.Zero(t, quantity.Cmp(actual), "%s = %s, expected %s", name, actual.String(), quantity.String())
+			}
+		})
+	}
+}
+
+func TestResolveInstanceTypes(t *testing.T) {
+	nodeGroups := []*NodeGroup{
+		{Nodegroup: &ekstypes.Nodegroup{InstanceTypes: []string{"m5.large", "x2gd.large"}}},
+		{Nodegroup: &ekstypes.Nodegroup{InstanceTypes: []string{"c5.xlarge"}}},
+	}
+	client := fakeInstanceTypeDescriber{
+		{
+			InstanceType: "x2gd.large",
+			VCpuInfo:     &ec2types.VCpuInfo{DefaultVCpus: lo.ToPtr(int32(2))},
+			MemoryInfo:   &ec2types.MemoryInfo{SizeInMiB: lo.ToPtr(int64(32768))},
+		},
+	}
+
+	require.NoError(t, resolveInstanceTypes(context.Background(), nodeGroups, client))
+	for _, n := range nodeGroups {
+		info, ok := n.instanceType("x2gd.large")
+		assert.True(t, ok)
+		assert.Equal(t, int64(32768), info.MemoryMiB)
+	}
+}
+
+func TestSumLimits(t *testing.T) {
+	got := sumLimits(
+		sigkarpenter.Limits{corev1.ResourceCPU: resource.MustParse("6"), corev1.ResourceMemory: resource.MustParse("24Gi")},
+		sigkarpenter.Limits{corev1.ResourceCPU: resource.MustParse("1000")},
+	)
+	assert.Len(t, got, 1)
+	cpu := got[corev1.ResourceCPU]
+	expected := resource.MustParse("1006")
+	assert.Zero(t, expected.Cmp(cpu))
+}
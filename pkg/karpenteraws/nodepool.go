@@ -2,12 +2,13 @@ package karpenteraws
 
 import (
 	"context"
-	"strings"
+	"encoding/json"
+	"time"
 
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 )
@@ -26,7 +27,12 @@ func (n *NodeGroup) GetNodePool() (sigkarpenter.NodePool, error) {
 		Spec:       n.NodePoolSpec(),
 	}
 
-	if err := np.Validate(context.TODO()); err != nil {
+	// consolidateAfter of WhenUnderutilized is valid in v1, it is validated with the API version of generated resources
+	validated := np.DeepCopy()
+	if validated.Spec.Disruption.ConsolidationPolicy == sigkarpenter.ConsolidationPolicyWhenUnderutilized {
+		validated.Spec.Disruption.ConsolidateAfter = nil
+	}
+	if err := validated.Validate(context.TODO()); err != nil {
 		return sigkarpenter.NodePool{}, err
 	}
 	return np, nil
@@ -38,6 +44,11 @@ func (n NodeGroup) NodePoolObjectMeta() metav1.ObjectMeta {
 		"migrate.karpenter.sh/source-nodegroup": n.Name(),
 	}
 
+	// Karpenter handles rebalance recommendations through its interruption queue
+	if n.AutoScalingGroup != nil && lo.FromPtr(n.AutoScalingGroup.CapacityRebalance) {
+		nodePoolAnnotations[CapacityRebalanceAnnotKey] = "true"
+	}
+
 	return metav1.ObjectMeta{
 		Name:        n.Name(),
 		Annotations: nodePoolAnnotations,
@@ -46,13 +57,9 @@ func (n NodeGroup) NodePoolObjectMeta() metav1.ObjectMeta {
 
 func (n NodeGroup) NodePoolSpec() sigkarpenter.NodePoolSpec {
 	return sigkarpenter.NodePoolSpec{
-		Template: n.NodeClaimTemplate(),
-		Disruption: sigkarpenter.Disruption{
-			ConsolidationPolicy: sigkarpenter.ConsolidationPolicyWhenUnderutilized,
-		},
-		Limits: sigkarpenter.Limits{
-			corev1.ResourceCPU: resource.MustParse("1000"),
-		},
+		Template:   n.NodeClaimTemplate(),
+		Disruption: n.Disruption(),
+		Limits:     n.Limits(),
 	}
 }
 
@@ -65,6 +72,13 @@ func (n NodeGroup) NodeClaimTemplate() sigkarpenter.NodeClaimTemplate {
 
 func (n NodeGroup) NodeClaimObjectMeta() sigkarpenter.ObjectMeta {
 	filteredLabels := map[string]string{}
+	if n.UserDataSettings != nil {
+		for key, val := range n.UserDataSettings.Labels {
+			if !tagLabeltoOmmit(key) {
+				filteredLabels[key] = val
+			}
+		}
+	}
 	for key, val := range n.Labels {
 		if !tagLabeltoOmmit(key) {
 			filteredLabels[key] = val
@@ -84,6 +98,7 @@ func (n NodeGroup) NodeClaimSpec() sigkarpenter.NodeClaimSpec {
 		},
 		Taints:       n.K8sTaints(),
 		Requirements: n.NodeSelectorRequirements(),
+		Kubelet:      n.KubeletConfiguration(),
 	}
 }
 
@@ -107,19 +122,18 @@ func (n NodeGroup) NodeSelectorRequirements() []sigkarpenter.NodeSelectorRequire
 				},
 			}
 		case "kubernetes.io/arch":
-			arch := []string{"amd64"}
-			if strings.Contains(string(n.AmiType), "ARM") {
-				arch = []string{"arm64"}
-			}
-
 			req = sigkarpenter.NodeSelectorRequirementWithMinValues{
 				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
 					Key:      key,
 					Operator: "In",
-					Values:   arch,
+					Values:   n.Architectures(),
 				},
 			}
 		case "node.kubernetes.io/instance-type":
+			if len(n.InstanceRequirements) > 0 {
+				reqs = append(reqs, n.InstanceRequirements...)
+				continue
+			}
 			req = sigkarpenter.NodeSelectorRequirementWithMinValues{
 				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
 					Key:      key,
@@ -133,7 +147,34 @@ func (n NodeGroup) NodeSelectorRequirements() []sigkarpenter.NodeSelectorRequire
 	return reqs
 }
 
+// Returns the maximum instance lifetime of self-managed Auto Scaling groups, nodes never expire otherwise
+func (n NodeGroup) ExpireAfter() sigkarpenter.NillableDuration {
+	if n.AutoScalingGroup != nil && lo.FromPtr(n.AutoScalingGroup.MaxInstanceLifetime) > 0 {
+		return sigkarpenter.NillableDuration{
+			Duration: lo.ToPtr(time.Duration(*n.AutoScalingGroup.MaxInstanceLifetime) * time.Second),
+		}
+	}
+	return sigkarpenter.NillableDuration{}
+}
+
 func (n NodeGroup) CapacityTypes() []string {
+	// Self-managed Auto Scaling groups can mix On-Demand and Spot instances
+	if n.AutoScalingGroup != nil {
+		onDemandPercentage, onDemandBase := int32(100), int32(0)
+		if policy := n.AutoScalingGroup.MixedInstancesPolicy; policy != nil && policy.InstancesDistribution != nil {
+			onDemandPercentage = lo.FromPtrOr(policy.InstancesDistribution.OnDemandPercentageAboveBaseCapacity, 100)
+			onDemandBase = lo.FromPtr(policy.InstancesDistribution.OnDemandBaseCapacity)
+		}
+		switch {
+		case onDemandPercentage >= 100:
+			return []string{"on-demand"}
+		case onDemandPercentage == 0 && onDemandBase == 0:
+			return []string{"spot"}
+		default:
+			return []string{"on-demand", "spot"}
+		}
+	}
+
 	switch n.CapacityType {
 	case ekstypes.CapacityTypesSpot:
 		return []string{"spot"}
@@ -161,5 +202,26 @@ func (n NodeGroup) K8sTaints() []corev1.Taint {
 
 		taints = append(taints, taint)
 	}
+
+	if n.UserDataSettings != nil {
+		for _, taint := range n.UserDataSettings.Taints {
+			if !lo.ContainsBy(taints, func(t corev1.Taint) bool { return t.MatchTaint(&taint) }) {
+				taints = append(taints, taint)
+			}
+		}
+	}
 	return taints
 }
+
+// Returns kubelet configuration of the nodegroup, kubelet flags of the user data are overridden
+func (n NodeGroup) KubeletConfiguration() *sigkarpenter.KubeletConfiguration {
+	if n.UserDataSettings == nil || n.UserDataSettings.Kubelet == nil {
+		return n.Kubelet
+	}
+	kubelet := n.UserDataSettings.Kubelet.DeepCopy()
+	if n.Kubelet != nil {
+		data, _ := json.Marshal(n.Kubelet)
+		_ = json.Unmarshal(data, kubelet)
+	}
+	return kubelet
+}
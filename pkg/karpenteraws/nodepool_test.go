@@ -4,6 +4,7 @@ import (
 	"reflect"
 	"testing"
 
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
@@ -136,7 +137,7 @@ func TestNodeGroup_NodeSelectorRequirements(t *testing.T) {
 			n: NodeGroup{
 				Nodegroup: &ekstypes.Nodegroup{
 					CapacityType:  ekstypes.CapacityTypesSpot,
-					InstanceTypes: []string{"t3.micro"},
+					InstanceTypes: []string{"t4g.micro"},
 					AmiType:       ekstypes.AMITypesBottlerocketArm64,
 				},
 			},
@@ -159,7 +160,7 @@ func TestNodeGroup_NodeSelectorRequirements(t *testing.T) {
 					NodeSelectorRequirement: corev1.NodeSelectorRequirement{
 						Key:      "node.kubernetes.io/instance-type",
 						Operator: "In",
-						Values:   []string{"t3.micro"},
+						Values:   []string{"t4g.micro"},
 					},
 				},
 			},
@@ -200,6 +201,43 @@ func TestNodeGroup_CapacityTypes(t *testing.T) {
 			},
 			expected: []string{"on-demand"},
 		},
+		{
+			name: "Auto Scaling group with Spot above On-Demand base capacity",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{},
+				AutoScalingGroup: &autoscalingtypes.AutoScalingGroup{
+					MixedInstancesPolicy: &autoscalingtypes.MixedInstancesPolicy{
+						InstancesDistribution: &autoscalingtypes.InstancesDistribution{
+							OnDemandBaseCapacity:                lo.ToPtr(int32(1)),
+							OnDemandPercentageAboveBaseCapacity: lo.ToPtr(int32(0)),
+						},
+					},
+				},
+			},
+			expected: []string{"on-demand", "spot"},
+		},
+		{
+			name: "Auto Scaling group with Spot only",
+			n: NodeGroup{
+				Nodegroup: &ekstypes.Nodegroup{},
+				AutoScalingGroup: &autoscalingtypes.AutoScalingGroup{
+					MixedInstancesPolicy: &autoscalingtypes.MixedInstancesPolicy{
+						InstancesDistribution: &autoscalingtypes.InstancesDistribution{
+							OnDemandPercentageAboveBaseCapacity: lo.ToPtr(int32(0)),
+						},
+					},
+				},
+			},
+			expected: []string{"spot"},
+		},
+		{
+			name: "Auto Scaling group without mixed instances policy",
+			n: NodeGroup{
+				Nodegroup:        &ekstypes.Nodegroup{},
+				AutoScalingGroup: &autoscalingtypes.AutoScalingGroup{},
+			},
+			expected: []string{"on-demand"},
+		},
 	}
 
 	for _, tt := range tests {
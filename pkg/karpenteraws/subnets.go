@@ -0,0 +1,125 @@
+package karpenteraws
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+
+	"github.com/punkwalker/karpenter-generate/pkg/aws"
+	"github.com/punkwalker/karpenter-generate/pkg/warnings"
+)
+
+// SubnetDescriber describes EC2 subnets, subnets which are not found are left out of the result
+type SubnetDescriber interface {
+	DescribeSubnets(ctx context.Context, ids []string, tagKeys []string) ([]ec2types.Subnet, error)
+}
+
+// Tag keys tried first when looking for a subnet selector, other keys are tried in alphabetical order
+var preferredSubnetTagKeys = []string{
+	"karpenter.sh/discovery",
+	"kubernetes.io/role/internal-elb",
+	"kubernetes.io/role/elb",
+	ClusterTagKey,
+}
+
+// SelectSubnetsByTags replaces the subnet IDs of the nodegroup by tags selecting exactly its subnets,
+// subnet IDs are kept with a warning when no such tags exist
+func (n *NodeGroup) SelectSubnetsByTags(ctx context.Context, client SubnetDescriber) error {
+	ids := lo.Uniq(n.Subnets)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	subnets, err := client.DescribeSubnets(ctx, ids, nil)
+	if err != nil {
+		return aws.FormatErrorAsMessageOnly(err)
+	}
+	if len(subnets) != len(ids) {
+		warnings.Warnf(`nodegroup "%s": subnets %s are not found, subnet IDs are used in "subnetSelectorTerms"`, n.Name(), strings.Join(ids, ","))
+		return nil
+	}
+
+	common := subnetTags(subnets[0])
+	for _, subnet := range subnets[1:] {
+		tags := subnetTags(subnet)
+		common = lo.PickBy(common, func(key, val string) bool { return tags[key] == val })
+	}
+
+	// Subnets of the account having any of the common tags, selectors can only match these
+	candidates := []ec2types.Subnet{}
+	if len(common) > 0 {
+		// Sorted so that requests are the same on every run, e.g. to replay them
+		keys := lo.Keys(common)
+		sort.Strings(keys)
+		if candidates, err = client.DescribeSubnets(ctx, nil, keys); err != nil {
+			return aws.FormatErrorAsMessageOnly(err)
+		}
+	}
+
+	for _, selector := range subnetTagSelectors(common) {
+		selected := lo.FilterMap(candidates, func(subnet ec2types.Subnet, _ int) (string, bool) {
+			tags := subnetTags(subnet)
+			return lo.FromPtr(subnet.SubnetId), lo.EveryBy(lo.Keys(selector), func(key string) bool {
+				val, ok := tags[key]
+				return ok && val == selector[key]
+			})
+		})
+		if len(selected) == len(ids) && lo.Every(ids, selected) {
+			n.SubnetTags = selector
+			return nil
+		}
+	}
+
+	warnings.Warnf(`nodegroup "%s": no subnet tags select exactly subnets %s, subnet IDs are used in "subnetSelectorTerms"`, n.Name(), strings.Join(ids, ","))
+	return nil
+}
+
+// Returns tags of the subnet which can be used in a selector, names and AWS reserved tags are specific to a subnet or stack
+func subnetTags(subnet ec2types.Subnet) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range subnet.Tags {
+		key := lo.FromPtr(tag.Key)
+		if key == "Name" || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		tags[key] = lo.FromPtr(tag.Value)
+	}
+	return tags
+}
+
+// Returns selectors made of single tags and pairs of tags in order of preference followed by all the tags,
+// if all the tags select other subnets too, no subset of them can select only the subnets of the nodegroup
+func subnetTagSelectors(tags map[string]string) []map[string]string {
+	keys := lo.Keys(tags)
+	rank := func(key string) int {
+		for idx, preferred := range preferredSubnetTagKeys {
+			if key == preferred || (strings.HasSuffix(preferred, "/") && strings.HasPrefix(key, preferred)) {
+				return idx
+			}
+		}
+		return len(preferredSubnetTagKeys)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if rank(keys[i]) != rank(keys[j]) {
+			return rank(keys[i]) < rank(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	selectors := []map[string]string{}
+	for i := range keys {
+		selectors = append(selectors, lo.PickByKeys(tags, keys[i:i+1]))
+	}
+	for i := range keys {
+		for j := i + 1; j < len(keys); j++ {
+			selectors = append(selectors, lo.PickByKeys(tags, []string{keys[i], keys[j]}))
+		}
+	}
+	if len(keys) > 2 {
+		selectors = append(selectors, tags)
+	}
+	return selectors
+}
@@ -0,0 +1,138 @@
+package karpenteraws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSubnetDescriber []ec2types.Subnet
+
+// Fails when tag keys are not sorted, requests must be the same on every run to be replayed
+func (f fakeSubnetDescriber) DescribeSubnets(_ context.Context, ids []string, tagKeys []string) ([]ec2types.Subnet, error) {
+	if !sort.StringsAreSorted(tagKeys) {
+		return nil, fmt.Errorf("tag keys %v are not sorted", tagKeys)
+	}
+	return lo.Filter(f, func(subnet ec2types.Subnet, _ int) bool {
+		if len(ids) > 0 && !lo.Contains(ids, *subnet.SubnetId) {
+			return false
+		}
+		return len(tagKeys) == 0 || lo.ContainsBy(subnet.Tags, func(tag ec2types.Tag) bool {
+			return lo.Contains(tagKeys, *tag.Key)
+		})
+	}), nil
+}
+
+func subnet(id string, tags map[string]string) ec2types.Subnet {
+	return ec2types.Subnet{
+		SubnetId: lo.ToPtr(id),
+		Tags: lo.MapToSlice(tags, func(key, val string) ec2types.Tag {
+			return ec2types.Tag{Key: lo.ToPtr(key), Value: lo.ToPtr(val)}
+		}),
+	}
+}
+
+func TestNodeGroup_SelectSubnetsByTags(t *testing.T) {
+	subnets := fakeSubnetDescriber{
+		subnet("subnet-private-a", map[string]string{
+			"Name":                             "private-a",
+			"aws:cloudformation:stack-name":    "vpc",
+			"kubernetes.io/role/internal-elb":  "1",
+			"kubernetes.io/cluster/my-cluster": "shared",
+			"karpenter.sh/discovery":           "my-cluster",
+			"environment":                      "prod",
+		}),
+		subnet("subnet-private-b", map[string]string{
+			"Name":                             "private-b",
+			"aws:cloudformation:stack-name":    "vpc",
+			"kubernetes.io/role/internal-elb":  "1",
+			"kubernetes.io/cluster/my-cluster": "shared",
+			"karpenter.sh/discovery":           "my-cluster",
+			"environment":                      "prod",
+		}),
+		subnet("subnet-public-a", map[string]string{
+			"Name":                             "public-a",
+			"aws:cloudformation:stack-name":    "vpc",
+			"kubernetes.io/role/elb":           "1",
+			"kubernetes.io/cluster/my-cluster": "shared",
+			"environment":                      "prod",
+		}),
+		subnet("subnet-other-cluster", map[string]string{
+			"kubernetes.io/role/internal-elb": "1",
+			"environment":                     "prod",
+		}),
+		subnet("subnet-app-a", map[string]string{"team": "a", "tier": "app"}),
+		subnet("subnet-db-a", map[string]string{"team": "a", "tier": "db"}),
+		subnet("subnet-app-b", map[string]string{"team": "b", "tier": "app"}),
+		subnet("subnet-untagged", nil),
+	}
+
+	tests := []struct {
+		name     string
+		subnets  []string
+		expected map[string]string
+	}{
+		{
+			name:     "Discovery tag is preferred",
+			subnets:  []string{"subnet-private-a", "subnet-private-b"},
+			expected: map[string]string{"karpenter.sh/discovery": "my-cluster"},
+		},
+		{
+			name:     "Role tag",
+			subnets:  []string{"subnet-public-a"},
+			expected: map[string]string{"kubernetes.io/role/elb": "1"},
+		},
+		{
+			name:     "Pair of tags",
+			subnets:  []string{"subnet-app-a"},
+			expected: map[string]string{"team": "a", "tier": "app"},
+		},
+		{
+			name:     "All the tags select other subnets",
+			subnets:  []string{"subnet-private-a", "subnet-public-a"},
+			expected: nil,
+		},
+		{
+			name:     "Subnets without tags",
+			subnets:  []string{"subnet-untagged"},
+			expected: nil,
+		},
+		{
+			name:     "Subnets not found",
+			subnets:  []string{"subnet-private-a", "subnet-deleted"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &NodeGroup{Nodegroup: &ekstypes.Nodegroup{NodegroupName: lo.ToPtr("ng"), Subnets: tt.subnets}}
+			require.NoError(t, n.SelectSubnetsByTags(context.Background(), subnets))
+			assert.Equal(t, tt.expected, n.SubnetTags)
+		})
+	}
+}
+
+func TestSubnetTagSelectors(t *testing.T) {
+	got := subnetTagSelectors(map[string]string{
+		"environment":                      "prod",
+		"kubernetes.io/cluster/my-cluster": "shared",
+		"kubernetes.io/role/internal-elb":  "1",
+	})
+	assert.Equal(t, []map[string]string{
+		{"kubernetes.io/role/internal-elb": "1"},
+		{"kubernetes.io/cluster/my-cluster": "shared"},
+		{"environment": "prod"},
+		{"kubernetes.io/role/internal-elb": "1", "kubernetes.io/cluster/my-cluster": "shared"},
+		{"kubernetes.io/role/internal-elb": "1", "environment": "prod"},
+		{"kubernetes.io/cluster/my-cluster": "shared", "environment": "prod"},
+		{"environment": "prod", "kubernetes.io/cluster/my-cluster": "shared", "kubernetes.io/role/internal-elb": "1"},
+	}, got)
+}
@@ -0,0 +1,522 @@
+package karpenteraws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	BootstrapScript      string = "/etc/eks/bootstrap.sh"
+	ShellScriptMediaType string = "text/x-shellscript"
+	NodeConfigMediaType  string = "application/node.eks.aws"
+	NodeConfigKind       string = "NodeConfig"
+)
+
+// Bootstrap flags which Karpenter sets for every node
+var bootstrapFlagsOwnedByKarpenter = map[string]bool{
+	"b64-cluster-ca":       true,
+	"apiserver-endpoint":   true,
+	"cluster-id":           true,
+	"container-runtime":    true,
+	"enable-local-outpost": true,
+	"ip-family":            true,
+	"service-ipv6-cidr":    true,
+	"use-max-pods":         true,
+}
+
+// UserDataSettings holds settings lifted out of bootstrap.sh invocations and nodeadm NodeConfig documents
+type UserDataSettings struct {
+	// AL2 when bootstrap.sh is invoked, AL2023 when a NodeConfig is found, empty otherwise
+	AMIFamily string
+	Kubelet   *sigkarpenter.KubeletConfiguration
+	Labels    map[string]string
+	Taints    []corev1.Taint
+	// Custom scripts and settings left after removing the bootstrap
+	UserData string
+	// Kubelet flags Karpenter does not support (e.g.: --v=2)
+	KubeletFlags []string
+	// bootstrap.sh arguments Karpenter does not support
+	BootstrapArgs map[string]string
+	// Labels and kubelet configuration which could not be migrated
+	Unsupported []string
+	// Lines invoking bootstrap.sh along with other commands, they are kept in the user data
+	BootstrapLines []string
+	// bootstrap.sh arguments referencing shell variables which are not assigned in the script, their invocation is kept in the user data
+	UnresolvedBootstrapArgs []string
+}
+
+// userDataParser collects settings of every bootstrap.sh invocation and NodeConfig document
+type userDataParser struct {
+	kubeletArgs
+	bootstrapArgs map[string]string
+}
+
+// MIMEPart is a part of MIME multipart user data
+type MIMEPart struct {
+	ContentType      string
+	TransferEncoding string
+	Body             string
+}
+
+// Returns MIME multipart user data in the format used by EC2 launch templates
+func MIMEUserData(boundary string, parts []MIMEPart) string {
+	userData := &strings.Builder{}
+	fmt.Fprintf(userData, "MIME-Version: 1.0\nContent-Type: multipart/mixed; boundary=\"%s\"\n\n", boundary)
+	for _, part := range parts {
+		fmt.Fprintf(userData, "--%s\nContent-Type: %s\n", boundary, part.ContentType)
+		if part.TransferEncoding != "" {
+			fmt.Fprintf(userData, "Content-Transfer-Encoding: %s\n", part.TransferEncoding)
+		}
+		fmt.Fprintf(userData, "\n%s\n", strings.TrimSuffix(part.Body, "\n")+"\n")
+	}
+	fmt.Fprintf(userData, "--%s--\n", boundary)
+	return userData.String()
+}
+
+// ParseUserData parses MIME multipart, shell script and nodeadm NodeConfig user data,
+// user data is returned as-is when it does not bootstrap the node
+func ParseUserData(userData string) (*UserDataSettings, error) {
+	parser := &userDataParser{
+		kubeletArgs:   kubeletArgs{config: map[string]any{}, labels: map[string]string{}},
+		bootstrapArgs: map[string]string{},
+	}
+	settings := &UserDataSettings{Labels: map[string]string{}}
+
+	var remaining string
+	var err error
+	if isMIME(userData) {
+		remaining, err = parseMIME(userData, parser, settings)
+	} else {
+		remaining, err = parseDocument(ShellScriptMediaType, userData, parser, settings)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.AMIFamily == "" {
+		settings.UserData = userData
+		return settings, nil
+	}
+	settings.UserData = remaining
+
+	kubelet, unsupportedKeys, err := KubeletFromConfig(parser.config)
+	if err != nil {
+		return nil, err
+	}
+	settings.Kubelet = kubelet
+	settings.Taints = parser.taints
+	settings.KubeletFlags = parser.unsupported
+	settings.BootstrapArgs = parser.bootstrapArgs
+	settings.Unsupported = unsupportedKeys
+	for key, val := range parser.labels {
+		if sigkarpenter.IsRestrictedLabel(key) != nil {
+			settings.Unsupported = append(settings.Unsupported, "--node-labels="+key)
+			continue
+		}
+		settings.Labels[key] = val
+	}
+	return settings, nil
+}
+
+func isMIME(userData string) bool {
+	return strings.HasPrefix(strings.TrimSpace(userData), "MIME-Version") ||
+		strings.HasPrefix(strings.TrimSpace(userData), "Content-Type: multipart")
+}
+
+// Splits MIME multipart user data into its boundary and parts
+func splitMIME(userData string) (string, []MIMEPart, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(strings.TrimSpace(userData) + "\n"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse MIME user data: %w", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse MIME user data: %w", err)
+	}
+
+	parts := []MIMEPart{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse MIME user data: %w", err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, MIMEPart{
+			ContentType:      part.Header.Get("Content-Type"),
+			TransferEncoding: part.Header.Get("Content-Transfer-Encoding"),
+			Body:             string(body),
+		})
+	}
+	return params["boundary"], parts, nil
+}
+
+// Parses every part of MIME multipart user data, parts without custom settings are dropped
+func parseMIME(userData string, parser *userDataParser, settings *UserDataSettings) (string, error) {
+	boundary, parts, err := splitMIME(userData)
+	if err != nil {
+		return "", err
+	}
+
+	remainingParts := []MIMEPart{}
+	for _, part := range parts {
+		part.Body, err = parsePart(part, parser, settings)
+		if err != nil {
+			return "", err
+		}
+		if part.Body != "" {
+			remainingParts = append(remainingParts, part)
+		}
+	}
+
+	if len(remainingParts) == 0 {
+		return "", nil
+	}
+	return MIMEUserData(boundary, remainingParts), nil
+}
+
+// Parses a MIME part, base64 parts are decoded and encoded again, parts of other encodings are kept as-is
+func parsePart(part MIMEPart, parser *userDataParser, settings *UserDataSettings) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(part.ContentType)
+	switch {
+	case part.TransferEncoding == "":
+		return parseDocument(mediaType, part.Body, parser, settings)
+	case strings.EqualFold(part.TransferEncoding, "base64"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(part.Body), ""))
+		if err != nil {
+			return part.Body, nil
+		}
+		doc, err := parseDocument(mediaType, string(decoded), parser, settings)
+		if err != nil || doc == "" {
+			return "", err
+		}
+		return encodeBase64(doc), nil
+	default:
+		return part.Body, nil
+	}
+}
+
+// Encodes a MIME part body in base64 lines of 76 characters
+func encodeBase64(body string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	lines := []string{}
+	for len(encoded) > 76 {
+		lines, encoded = append(lines, encoded[:76]), encoded[76:]
+	}
+	return strings.Join(append(lines, encoded), "\n")
+}
+
+// Returns the document without the settings lifted out of it, empty if nothing custom is left
+func parseDocument(mediaType, doc string, parser *userDataParser, settings *UserDataSettings) (string, error) {
+	switch mediaType {
+	case ShellScriptMediaType:
+		return parseShellScript(doc, parser, settings), nil
+	case NodeConfigMediaType:
+		return parseNodeConfig(doc, parser, settings)
+	default:
+		return doc, nil
+	}
+}
+
+// Removes bootstrap.sh invocations from the script and parses their arguments, shell variables of the arguments are
+// resolved from the assignments of the script and assignments only used by removed invocations are removed as well
+func parseShellScript(script string, parser *userDataParser, settings *UserDataSettings) string {
+	lines := []string{}
+	vars := shellVariables{values: map[string]string{}, assignments: map[string][]int{}, dynamic: map[string]bool{}}
+	// Variables referenced by removed invocations
+	lifted := []string{}
+	var continued string
+	for _, line := range strings.Split(script, "\n") {
+		// Join lines continued with a backslash
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line, continued = continued+line, ""
+
+		if args, ok := bootstrapInvocation(line); ok {
+			flags, unresolved := vars.resolve(bootstrapFlags(shellFields(args)))
+			if len(unresolved) == 0 {
+				settings.AMIFamily = awskarpenter.AMIFamilyAL2
+				parseBootstrapArgs(flags, parser)
+				lifted = append(lifted, variableNames(args)...)
+				continue
+			}
+			settings.UnresolvedBootstrapArgs = append(settings.UnresolvedBootstrapArgs, unresolved...)
+		} else if strings.Contains(line, BootstrapScript) {
+			settings.BootstrapLines = append(settings.BootstrapLines, strings.TrimSpace(line))
+		}
+		vars.assign(line, len(lines))
+		lines = append(lines, line)
+	}
+
+	// Assignments are removed when no line left references their variable
+	referenced := lo.FlatMap(lines, func(line string, _ int) []string { return variableNames(line) })
+	removed := map[int]bool{}
+	for _, name := range lo.Uniq(lifted) {
+		if !vars.dynamic[name] && !lo.Contains(referenced, name) {
+			for _, idx := range vars.assignments[name] {
+				removed[idx] = true
+			}
+		}
+	}
+	lines = lo.Reject(lines, func(_ string, idx int) bool { return removed[idx] })
+
+	custom := lo.ContainsBy(lines, func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+		return trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "set ")
+	})
+	if !custom {
+		return ""
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Variable assignments of a shell script (e.g.: "API_SERVER_URL=https://...")
+type shellVariables struct {
+	// Values of variables assigned a single word without expansions
+	values map[string]string
+	// Indexes of the lines assigning a value to the variable
+	assignments map[string][]int
+	// Variables assigned with expansions or command substitutions, their value is unknown
+	dynamic map[string]bool
+}
+
+var assignmentRegex = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// Records the value of the variable assigned by the line at index idx
+func (v shellVariables) assign(line string, idx int) {
+	match := assignmentRegex.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+	name, val := match[1], match[2]
+	v.assignments[name] = append(v.assignments[name], idx)
+	fields := shellFields(val)
+	if !isSimpleCommand(val) || strings.Contains(val, "$") || len(fields) > 1 {
+		delete(v.values, name)
+		v.dynamic[name] = true
+		return
+	}
+	v.values[name] = strings.Join(fields, "")
+}
+
+// Replaces shell variables in the values of the flags, values of flags which are not owned by Karpenter
+// referencing variables without a known value are returned as unresolved
+func (v shellVariables) resolve(flags []bootstrapFlag) ([]bootstrapFlag, []string) {
+	unresolved := []string{}
+	resolved := make([]bootstrapFlag, 0, len(flags))
+	for _, flag := range flags {
+		known := true
+		val := os.Expand(flag.val, func(name string) string {
+			val, ok := v.values[name]
+			known = known && ok
+			return val
+		})
+		if !known && !bootstrapFlagsOwnedByKarpenter[flag.name] {
+			unresolved = append(unresolved, "--"+flag.name+" "+flag.val)
+		}
+		resolved = append(resolved, bootstrapFlag{name: flag.name, val: val})
+	}
+	return resolved, unresolved
+}
+
+// Returns the names of the shell variables referenced in the text
+func variableNames(text string) []string {
+	names := []string{}
+	os.Expand(text, func(name string) string {
+		names = append(names, name)
+		return ""
+	})
+	return names
+}
+
+// Returns the arguments of a line which only invokes bootstrap.sh (e.g.: "exec /etc/eks/bootstrap.sh my-cluster"),
+// lines running other commands in the same line are not invocations
+func bootstrapInvocation(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"exec ", "sudo ", "/bin/bash ", "/bin/sh ", "bash ", "sh "} {
+		line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	}
+	args, found := strings.CutPrefix(line, BootstrapScript)
+	if !found || (args != "" && args[0] != ' ' && args[0] != '\t') {
+		return "", false
+	}
+	return args, isSimpleCommand(args)
+}
+
+// Reports whether the arguments end the command, without pipes, lists, redirections or command substitutions
+func isSimpleCommand(args string) bool {
+	var quote rune
+	for idx, r := range args {
+		switch {
+		case quote == '\'' && r == quote:
+			quote = 0
+		case quote == '\'':
+		case r == '`' || (r == '$' && strings.HasPrefix(args[idx:], "$(")):
+			return false
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '\'' || r == '"':
+			quote = r
+		case strings.ContainsRune(";&|<>", r):
+			return false
+		case r == '#' && (idx == 0 || args[idx-1] == ' ' || args[idx-1] == '\t'):
+			// Trailing comment
+			return true
+		}
+	}
+	return true
+}
+
+// bootstrap.sh flag and its value
+type bootstrapFlag struct {
+	name string
+	val  string
+}
+
+// Returns the flags of bootstrap.sh arguments, positional arguments (e.g.: the cluster name) are skipped
+func bootstrapFlags(args []string) []bootstrapFlag {
+	flags := []bootstrapFlag{}
+	for idx := 0; idx < len(args); idx++ {
+		if !strings.HasPrefix(args[idx], "--") {
+			continue
+		}
+		name, val, hasVal := strings.Cut(strings.TrimPrefix(args[idx], "--"), "=")
+		if !hasVal && idx+1 < len(args) {
+			idx++
+			val = args[idx]
+		}
+		flags = append(flags, bootstrapFlag{name: name, val: val})
+	}
+	return flags
+}
+
+// Parses bootstrap.sh flags
+func parseBootstrapArgs(flags []bootstrapFlag, parser *userDataParser) {
+	for _, flag := range flags {
+		switch {
+		case flag.name == "kubelet-extra-args":
+			parseKubeletArgs(shellFields(flag.val), &parser.kubeletArgs)
+		case flag.name == "dns-cluster-ip":
+			parser.config["clusterDNS"] = []string{flag.val}
+		case bootstrapFlagsOwnedByKarpenter[flag.name]:
+		default:
+			parser.bootstrapArgs[flag.name] = flag.val
+		}
+	}
+}
+
+// Removes the cluster and kubelet settings from a nodeadm NodeConfig, settings Karpenter does not support are kept
+func parseNodeConfig(doc string, parser *userDataParser, settings *UserDataSettings) (string, error) {
+	nodeConfig := map[string]any{}
+	if err := yaml.Unmarshal([]byte(doc), &nodeConfig); err != nil {
+		return "", fmt.Errorf("failed to parse NodeConfig user data: %w", err)
+	}
+	if nodeConfig["kind"] != NodeConfigKind {
+		return doc, nil
+	}
+	settings.AMIFamily = awskarpenter.AMIFamilyAL2023
+
+	spec, _ := nodeConfig["spec"].(map[string]any)
+	delete(spec, "cluster")
+
+	if kubelet, ok := spec["kubelet"].(map[string]any); ok {
+		config, _ := kubelet["config"].(map[string]any)
+		for key, val := range config {
+			if supported, _, err := KubeletFromConfig(map[string]any{key: val}); err == nil && supported != nil {
+				parser.config[key] = val
+				delete(config, key)
+			}
+		}
+
+		flags := []string{}
+		flagValues, _ := kubelet["flags"].([]any)
+		for _, flag := range flagValues {
+			flagParser := &kubeletArgs{config: parser.config, labels: parser.labels}
+			parseKubeletArgs(shellFields(fmt.Sprint(flag)), flagParser)
+			parser.taints = append(parser.taints, flagParser.taints...)
+			if len(flagParser.unsupported) > 0 {
+				flags = append(flags, fmt.Sprint(flag))
+			}
+		}
+
+		if len(config) == 0 {
+			delete(kubelet, "config")
+		}
+		if len(flags) == 0 {
+			delete(kubelet, "flags")
+		} else {
+			kubelet["flags"] = flags
+		}
+		if len(kubelet) == 0 {
+			delete(spec, "kubelet")
+		}
+	}
+
+	if len(spec) == 0 {
+		return "", nil
+	}
+	data, err := yaml.Marshal(nodeConfig)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Splits a shell command line into words, quotes are removed
+func shellFields(line string) []string {
+	fields := []string{}
+	var field strings.Builder
+	var quote rune
+	inField := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			field.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inField = r, true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		case r == ';' || r == '&' || r == '|':
+			// End of the command
+			if inField {
+				fields = append(fields, field.String())
+			}
+			return fields
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
@@ -0,0 +1,282 @@
+package karpenteraws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func TestParseUserData(t *testing.T) {
+	tests := []struct {
+		name     string
+		userData string
+		expected *UserDataSettings
+	}{
+		{
+			name: "Shell script with bootstrap.sh",
+			userData: `#!/bin/bash
+set -o xtrace
+/etc/eks/bootstrap.sh my-cluster --b64-cluster-ca abc --dns-cluster-ip 172.20.0.10 --local-disks raid0 \
+  --kubelet-extra-args "--max-pods=29 --node-labels=team=data,node.kubernetes.io/lifecycle=spot --register-with-taints=dedicated=data:NoSchedule --kube-reserved cpu=250m --v=2"
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "AL2",
+				Kubelet: &sigkarpenter.KubeletConfiguration{
+					ClusterDNS:   []string{"172.20.0.10"},
+					MaxPods:      lo.ToPtr(int32(29)),
+					KubeReserved: map[string]string{"cpu": "250m"},
+				},
+				Labels: map[string]string{"team": "data", "node.kubernetes.io/lifecycle": "spot"},
+				Taints: []corev1.Taint{
+					{Key: "dedicated", Value: "data", Effect: corev1.TaintEffectNoSchedule},
+				},
+				UserData:      "",
+				KubeletFlags:  []string{"--v=2"},
+				BootstrapArgs: map[string]string{"local-disks": "raid0"},
+				Unsupported:   []string{},
+			},
+		},
+		{
+			name: "MIME multipart with nodeadm NodeConfig and custom script",
+			userData: `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: application/node.eks.aws
+
+apiVersion: node.eks.aws/v1alpha1
+kind: NodeConfig
+spec:
+  cluster:
+    name: my-cluster
+  kubelet:
+    config:
+      maxPods: 110
+      shutdownGracePeriod: 30s
+    flags:
+    - --node-labels=team=web
+
+--BOUNDARY
+Content-Type: text/x-shellscript
+
+#!/bin/bash
+yum install -y htop
+
+--BOUNDARY--
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "AL2023",
+				Kubelet: &sigkarpenter.KubeletConfiguration{
+					MaxPods: lo.ToPtr(int32(110)),
+				},
+				Labels:        map[string]string{"team": "web"},
+				BootstrapArgs: map[string]string{},
+				Unsupported:   []string{},
+				UserData: `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: application/node.eks.aws
+
+apiVersion: node.eks.aws/v1alpha1
+kind: NodeConfig
+spec:
+  kubelet:
+    config:
+      shutdownGracePeriod: 30s
+
+--BOUNDARY
+Content-Type: text/x-shellscript
+
+#!/bin/bash
+yum install -y htop
+
+--BOUNDARY--
+`,
+			},
+		},
+		{
+			name: "MIME multipart with base64 encoded script",
+			userData: `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: text/x-shellscript
+Content-Transfer-Encoding: base64
+
+IyEvYmluL2Jhc2gKL2V0Yy9la3MvYm9vdHN0cmFwLnNoIG15LWNsdXN0ZXIgLS1rdWJlbGV0LWV4
+dHJhLWFyZ3MgJy0tbWF4LXBvZHM9MjAnCnl1bSBpbnN0YWxsIC15IGh0b3AK
+
+--BOUNDARY--
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "AL2",
+				Kubelet: &sigkarpenter.KubeletConfiguration{
+					MaxPods: lo.ToPtr(int32(20)),
+				},
+				Labels:        map[string]string{},
+				BootstrapArgs: map[string]string{},
+				Unsupported:   []string{},
+				UserData: `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="BOUNDARY"
+
+--BOUNDARY
+Content-Type: text/x-shellscript
+Content-Transfer-Encoding: base64
+
+IyEvYmluL2Jhc2gKeXVtIGluc3RhbGwgLXkgaHRvcAo=
+
+--BOUNDARY--
+`,
+			},
+		},
+		{
+			name:     "bootstrap.sh in a compound command is kept",
+			userData: "#!/bin/bash\n/etc/eks/bootstrap.sh my-cluster --kubelet-extra-args '--max-pods=20' && echo done\n",
+			expected: &UserDataSettings{
+				Labels:         map[string]string{},
+				UserData:       "#!/bin/bash\n/etc/eks/bootstrap.sh my-cluster --kubelet-extra-args '--max-pods=20' && echo done\n",
+				BootstrapLines: []string{"/etc/eks/bootstrap.sh my-cluster --kubelet-extra-args '--max-pods=20' && echo done"},
+			},
+		},
+		{
+			name: "EKS managed nodegroup script with shell variables",
+			userData: `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="//"
+
+--//
+Content-Type: text/x-shellscript; charset="us-ascii"
+
+#!/bin/bash
+set -ex
+B64_CLUSTER_CA=LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0t
+API_SERVER_URL=https://0123456789ABCDEF0123456789ABCDEF.gr7.us-west-2.eks.amazonaws.com
+K8S_CLUSTER_DNS_IP=10.100.0.10
+/etc/eks/bootstrap.sh my-cluster --kubelet-extra-args '--node-labels=eks.amazonaws.com/nodegroup-image=ami-0123456789abcdef0,eks.amazonaws.com/capacityType=ON_DEMAND,eks.amazonaws.com/nodegroup=ng --max-pods=17' --b64-cluster-ca $B64_CLUSTER_CA --apiserver-endpoint $API_SERVER_URL --dns-cluster-ip $K8S_CLUSTER_DNS_IP --use-max-pods false
+
+--//--
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "AL2",
+				Kubelet: &sigkarpenter.KubeletConfiguration{
+					ClusterDNS: []string{"10.100.0.10"},
+					MaxPods:    lo.ToPtr(int32(17)),
+				},
+				Labels: map[string]string{
+					"eks.amazonaws.com/capacityType":    "ON_DEMAND",
+					"eks.amazonaws.com/nodegroup":       "ng",
+					"eks.amazonaws.com/nodegroup-image": "ami-0123456789abcdef0",
+				},
+				BootstrapArgs: map[string]string{},
+				Unsupported:   []string{},
+			},
+		},
+		{
+			name: "Variables used by other commands are kept",
+			userData: `#!/bin/bash
+CLUSTER_DNS=172.20.0.10
+echo "nameserver $CLUSTER_DNS" >> /etc/resolv.conf
+/etc/eks/bootstrap.sh my-cluster --dns-cluster-ip "${CLUSTER_DNS}"
+`,
+			expected: &UserDataSettings{
+				AMIFamily: "AL2",
+				Kubelet: &sigkarpenter.KubeletConfiguration{
+					ClusterDNS: []string{"172.20.0.10"},
+				},
+				Labels:        map[string]string{},
+				BootstrapArgs: map[string]string{},
+				Unsupported:   []string{},
+				UserData: `#!/bin/bash
+CLUSTER_DNS=172.20.0.10
+echo "nameserver $CLUSTER_DNS" >> /etc/resolv.conf
+`,
+			},
+		},
+		{
+			name: "bootstrap.sh with unassigned variables is kept",
+			userData: `#!/bin/bash
+MAX_PODS=$(/etc/eks/max-pods-calculator.sh --instance-type-from-imds --cni-version 1.10.0)
+/etc/eks/bootstrap.sh my-cluster --b64-cluster-ca $B64_CLUSTER_CA --kubelet-extra-args "--max-pods=$MAX_PODS"
+`,
+			expected: &UserDataSettings{
+				Labels: map[string]string{},
+				UserData: `#!/bin/bash
+MAX_PODS=$(/etc/eks/max-pods-calculator.sh --instance-type-from-imds --cni-version 1.10.0)
+/etc/eks/bootstrap.sh my-cluster --b64-cluster-ca $B64_CLUSTER_CA --kubelet-extra-args "--max-pods=$MAX_PODS"
+`,
+				UnresolvedBootstrapArgs: []string{"--kubelet-extra-args --max-pods=$MAX_PODS"},
+			},
+		},
+		{
+			name:     "Script without bootstrap is kept as-is",
+			userData: "#!/bin/bash\necho hello\n",
+			expected: &UserDataSettings{
+				Labels:   map[string]string{},
+				UserData: "#!/bin/bash\necho hello\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserData(tt.userData)
+			if err != nil {
+				t.Fatalf("ParseUserData() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseUserData() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBootstrapInvocation(t *testing.T) {
+	tests := []struct {
+		line         string
+		expectedArgs string
+		expectedOK   bool
+	}{
+		{line: `/etc/eks/bootstrap.sh my-cluster --kubelet-extra-args '--v=2 | tee'`, expectedArgs: ` my-cluster --kubelet-extra-args '--v=2 | tee'`, expectedOK: true},
+		{line: `  exec /etc/eks/bootstrap.sh my-cluster # bootstrap`, expectedArgs: ` my-cluster # bootstrap`, expectedOK: true},
+		{line: `/etc/eks/bootstrap.sh my-cluster; echo done`, expectedArgs: ` my-cluster; echo done`},
+		{line: `/etc/eks/bootstrap.sh "$(cat /etc/cluster-name)"`, expectedArgs: ` "$(cat /etc/cluster-name)"`},
+		{line: `[ -f /etc/eks/bootstrap.sh ] && /etc/eks/bootstrap.sh my-cluster`},
+		{line: `/etc/eks/bootstrap.sh.bak my-cluster`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			args, ok := bootstrapInvocation(tt.line)
+			if ok != tt.expectedOK || (ok && args != tt.expectedArgs) {
+				t.Errorf("bootstrapInvocation() = %q, %v, expected %q, %v", args, ok, tt.expectedArgs, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestShellFields(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{
+			line:     ` my-cluster --kubelet-extra-args '--max-pods=29 --v=2' --dns-cluster-ip "10.0.0.10"`,
+			expected: []string{"my-cluster", "--kubelet-extra-args", "--max-pods=29 --v=2", "--dns-cluster-ip", "10.0.0.10"},
+		},
+		{
+			line:     ` my-cluster && echo done`,
+			expected: []string{"my-cluster"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := shellFields(tt.line); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("shellFields() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package karpenteraws
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+
+	karpenterv1 "github.com/punkwalker/karpenter-generate/pkg/apis/v1"
+)
+
+var (
+	NodePoolV1TypeMeta = metav1.TypeMeta{
+		Kind:       "NodePool",
+		APIVersion: karpenterv1.SchemeGroupVersion.Identifier(),
+	}
+	NodeClassV1TypeMeta = metav1.TypeMeta{
+		Kind:       "EC2NodeClass",
+		APIVersion: karpenterv1.AWSSchemeGroupVersion.Identifier(),
+	}
+)
+
+// Converts generated v1beta1 resources into karpenter.sh/v1 and karpenter.k8s.aws/v1 resources
+func toV1(nodePools []sigkarpenter.NodePool, nodeClasses []awskarpenter.EC2NodeClass) ([]runtime.Object, error) {
+	objs := []runtime.Object{}
+	ncMap := map[string]*karpenterv1.EC2NodeClass{}
+	ncV1 := []*karpenterv1.EC2NodeClass{}
+	for _, nc := range nodeClasses {
+		converted := NodeClassV1(nc)
+		ncMap[converted.Name] = &converted
+		ncV1 = append(ncV1, &converted)
+	}
+
+	for _, np := range nodePools {
+		converted := NodePoolV1(np)
+		if err := converted.Validate(); err != nil {
+			return nil, err
+		}
+
+		// Kubelet configuration is part of the EC2NodeClass in v1
+		if kubelet := np.Spec.Template.Spec.Kubelet; kubelet != nil {
+			nc, ok := ncMap[np.Spec.Template.Spec.NodeClassRef.Name]
+			if !ok {
+				return nil, fmt.Errorf(`nodepool "%s" references unknown ec2nodeclass "%s"`, np.Name, np.Spec.Template.Spec.NodeClassRef.Name)
+			}
+			if nc.Spec.Kubelet != nil && !equality.Semantic.DeepEqual(nc.Spec.Kubelet, kubelet) {
+				return nil, fmt.Errorf(`nodepools referencing ec2nodeclass "%s" have different kubelet configuration`, nc.Name)
+			}
+			nc.Spec.Kubelet = kubelet.DeepCopy()
+		}
+		objs = append(objs, &converted)
+	}
+
+	for _, nc := range ncV1 {
+		if err := nc.Validate(); err != nil {
+			return nil, err
+		}
+		objs = append(objs, nc)
+	}
+	return objs, nil
+}
+
+func NodePoolV1(np sigkarpenter.NodePool) karpenterv1.NodePool {
+	spec := np.Spec.Template.Spec
+
+	consolidationPolicy := karpenterv1.ConsolidationPolicyWhenEmptyOrUnderutilized
+	if np.Spec.Disruption.ConsolidationPolicy == sigkarpenter.ConsolidationPolicyWhenEmpty {
+		consolidationPolicy = karpenterv1.ConsolidationPolicyWhenEmpty
+	}
+
+	consolidateAfter := sigkarpenter.NillableDuration{Duration: lo.ToPtr(time.Duration(0))}
+	if np.Spec.Disruption.ConsolidateAfter != nil {
+		consolidateAfter = *np.Spec.Disruption.ConsolidateAfter.DeepCopy()
+	}
+
+	npV1 := karpenterv1.NodePool{
+		TypeMeta:   NodePoolV1TypeMeta,
+		ObjectMeta: *np.ObjectMeta.DeepCopy(),
+		Spec: karpenterv1.NodePoolSpec{
+			Template: karpenterv1.NodeClaimTemplate{
+				ObjectMeta: *np.Spec.Template.ObjectMeta.DeepCopy(),
+				Spec: karpenterv1.NodeClaimTemplateSpec{
+					Taints:        spec.Taints,
+					StartupTaints: spec.StartupTaints,
+					Requirements:  spec.Requirements,
+					ExpireAfter:   np.Spec.Disruption.ExpireAfter,
+				},
+			},
+			Disruption: karpenterv1.Disruption{
+				ConsolidateAfter:    consolidateAfter,
+				ConsolidationPolicy: consolidationPolicy,
+			},
+			Limits: np.Spec.Limits,
+			Weight: np.Spec.Weight,
+		},
+	}
+
+	if spec.NodeClassRef != nil {
+		npV1.Spec.Template.Spec.NodeClassRef = &karpenterv1.NodeClassReference{
+			Kind:  spec.NodeClassRef.Kind,
+			Name:  spec.NodeClassRef.Name,
+			Group: karpenterv1.AWSGroup,
+		}
+	}
+
+	for _, budget := range np.Spec.Disruption.Budgets {
+		npV1.Spec.Disruption.Budgets = append(npV1.Spec.Disruption.Budgets, karpenterv1.Budget{
+			Nodes:    budget.Nodes,
+			Schedule: budget.Schedule,
+			Duration: budget.Duration,
+		})
+	}
+	return *npV1.DeepCopy()
+}
+
+func NodeClassV1(nc awskarpenter.EC2NodeClass) karpenterv1.EC2NodeClass {
+	ncV1 := karpenterv1.EC2NodeClass{
+		TypeMeta:   NodeClassV1TypeMeta,
+		ObjectMeta: *nc.ObjectMeta.DeepCopy(),
+		Spec: karpenterv1.EC2NodeClassSpec{
+			SubnetSelectorTerms:        nc.Spec.SubnetSelectorTerms,
+			SecurityGroupSelectorTerms: nc.Spec.SecurityGroupSelectorTerms,
+			AssociatePublicIPAddress:   nc.Spec.AssociatePublicIPAddress,
+			AMISelectorTerms:           AMISelectorTermsV1(nc.Spec.AMIFamily, nc.Spec.AMISelectorTerms),
+			UserData:                   nc.Spec.UserData,
+			Role:                       nc.Spec.Role,
+			InstanceProfile:            nc.Spec.InstanceProfile,
+			Tags:                       nc.Spec.Tags,
+			BlockDeviceMappings:        nc.Spec.BlockDeviceMappings,
+			InstanceStorePolicy:        nc.Spec.InstanceStorePolicy,
+			DetailedMonitoring:         nc.Spec.DetailedMonitoring,
+			MetadataOptions:            nc.Spec.MetadataOptions,
+			Context:                    nc.Spec.Context,
+		},
+	}
+
+	// amiFamily is inferred from the alias, it is only kept for explicit AMI selectors
+	if len(nc.Spec.AMISelectorTerms) > 0 {
+		ncV1.Spec.AMIFamily = nc.Spec.AMIFamily
+	}
+	return *ncV1.DeepCopy()
+}
+
+// Returns v1 AMISelectorTerms, EKS optimized AMI families without selector terms are replaced by an alias
+func AMISelectorTermsV1(amiFamily *string, terms []awskarpenter.AMISelectorTerm) []karpenterv1.AMISelectorTerm {
+	termsV1 := []karpenterv1.AMISelectorTerm{}
+	for _, term := range terms {
+		termsV1 = append(termsV1, karpenterv1.AMISelectorTerm{
+			Tags:  term.Tags,
+			ID:    term.ID,
+			Name:  term.Name,
+			Owner: term.Owner,
+		})
+	}
+
+	if len(termsV1) == 0 && amiFamily != nil && *amiFamily != awskarpenter.AMIFamilyCustom {
+		termsV1 = append(termsV1, karpenterv1.AMISelectorTerm{
+			Alias: fmt.Sprintf("%s@latest", strings.ToLower(*amiFamily)),
+		})
+	}
+	return termsV1
+}
@@ -2,10 +2,22 @@ package options
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+const (
+	APIVersionV1beta1 = "v1beta1"
+	APIVersionV1      = "v1"
+	AMIFamilyAL2023   = "AL2023"
+	OutputHelmChart   = "helm-chart"
+	OutputTerraform   = "terraform"
+	// Number of nodegroups described at the same time
+	DefaultConcurrency = 10
+)
+
 type Options struct {
 	ClusterName            string
 	NodegroupName          string
@@ -13,10 +25,74 @@ type Options struct {
 	Profile                string
 	Region                 string
 	Output                 string
+	APIVersion             string
+	InputDir               string
+	Record                 string
+	Replay                 string
+	Concurrency            int
+	Timeout                time.Duration
+	AutoScalingGroups      []string
+	AutoScalingGroupTag    string
+	TargetAMIFamily        string
+	SubnetTags             bool
+	PinAMI                 bool
+	LimitsHeadroom         float64
+	ExpandInstanceTypes    bool
+	MultiArch              bool
+	DisruptionPolicyFile   string
+	ConsolidationPolicy    string
+	ConsolidateAfter       string
+	ExpireAfter            string
+	ConfigFile             string
+	NodeRole               string
+	Kubeconfig             string
+	KubeContext            string
+	Apply                  bool
+	LiveDir                string
+	OutputDir              string
+	Overlays               bool
+	TerraformVariables     bool
 	Debug                  bool
 }
 
 func New(cmd *cobra.Command) *Options {
+	opts := newGenerate(cmd)
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "yaml", "output format (yaml, json, helm-chart or terraform)")
+	cmd.Flags().BoolVar(&opts.TerraformVariables, "terraform-variables", false, "replace subnet, security group and AMI IDs and roles by Terraform variables")
+	cmd.Flags().BoolVar(&opts.Apply, "apply", false, "server-side apply generated resources to the cluster instead of printing them")
+	cmd.Flags().StringVar(&opts.Kubeconfig, "kubeconfig", "", "kubeconfig file used by --apply (default: $KUBECONFIG or ~/.kube/config, the EKS cluster endpoint and an IAM token when there is none)")
+	cmd.Flags().StringVar(&opts.KubeContext, "context", "", "kubeconfig context used by --apply")
+	addOutputDirFlags(cmd, opts)
+	cmd.SetHelpFunc(usage)
+
+	return opts
+}
+
+// NewDiff adds flags of the diff command
+func NewDiff(cmd *cobra.Command) *Options {
+	opts := newGenerate(cmd)
+	cmd.Flags().StringVar(&opts.LiveDir, "live-dir", "", "directory with manifests of live resources, the cluster is not called")
+	cmd.Flags().StringVar(&opts.Kubeconfig, "kubeconfig", "", "kubeconfig file of the cluster (default: $KUBECONFIG or ~/.kube/config, the EKS cluster endpoint and an IAM token when there is none)")
+	cmd.Flags().StringVar(&opts.KubeContext, "context", "", "kubeconfig context of the cluster")
+	cmd.SetHelpFunc(diffUsage)
+
+	return opts
+}
+
+func addOutputDirFlags(cmd *cobra.Command, opts *Options) {
+	cmd.Flags().StringVar(&opts.OutputDir, "output-dir", "", "directory to write one file per resource and a kustomization.yaml to instead of printing them")
+	cmd.Flags().BoolVar(&opts.Overlays, "overlays", false, "write a kustomize overlay per source nodegroup to overlays/<nodegroup> of --output-dir")
+}
+
+func addDisruptionFlags(cmd *cobra.Command, opts *Options) {
+	cmd.Flags().StringVar(&opts.DisruptionPolicyFile, "disruption-policy", "", "YAML file with disruption settings of all the NodePools and of NodePools of specific nodegroups")
+	cmd.Flags().StringVar(&opts.ConsolidationPolicy, "consolidation-policy", "", "consolidation policy of all the NodePools (WhenEmpty, WhenUnderutilized or WhenEmptyOrUnderutilized)")
+	cmd.Flags().StringVar(&opts.ConsolidateAfter, "consolidate-after", "", "consolidateAfter of all the NodePools (e.g.: 10m or Never)")
+	cmd.Flags().StringVar(&opts.ExpireAfter, "expire-after", "", "expireAfter of all the NodePools (e.g.: 720h or Never)")
+}
+
+// Adds flags selecting the nodegroups resources are generated from
+func newGenerate(cmd *cobra.Command) *Options {
 	opts := Options{}
 	cmd.Flags().StringVar(&opts.Profile, "profile", "", "use the specific profile from your credential file")
 	cmd.Flags().StringVar(&opts.Region, "region", "", "the region to use, overrides config/env settings")
@@ -24,22 +100,194 @@ func New(cmd *cobra.Command) *Options {
 	cmd.Flags().StringVar(&opts.ClusterName, "cluster", "", "name of the EKS cluster")
 	cmd.Flags().StringVar(&opts.NodegroupName, "nodegroup", "", "name of the EKS managed nodegroup")
 	cmd.Flags().StringVar(&opts.KarpenterNodegroupName, "karpenter-nodegroup", "", "name of the EKS managed nodegroup running Karpenter deployment")
-	cmd.Flags().StringVarP(&opts.Output, "output", "o", "yaml", "name of the EKS managed nodegroup running Karpenter deployment")
+	cmd.Flags().StringVar(&opts.APIVersion, "api-version", APIVersionV1beta1, "karpenter API version of generated resources (v1beta1 or v1)")
+	cmd.Flags().StringVar(&opts.InputDir, "input-dir", "", "directory with saved describe-nodegroup and describe-launch-template-versions JSON output, AWS APIs are not called")
+	cmd.Flags().StringSliceVar(&opts.AutoScalingGroups, "asg", nil, "names of self-managed Auto Scaling groups to convert")
+	cmd.Flags().StringVar(&opts.AutoScalingGroupTag, "asg-tag", "", "tag of self-managed Auto Scaling groups to convert (e.g.: kubernetes.io/cluster/<Cluster Name>=owned)")
+	cmd.Flags().StringVar(&opts.TargetAMIFamily, "target-ami-family", "", "AMI family to move AL2 nodegroups to (AL2023)")
+	cmd.Flags().Float64Var(&opts.LimitsHeadroom, "limits-headroom", 1, "factor applied to the capacity of nodegroups (max size times the largest instance type) in NodePool limits")
+	cmd.Flags().BoolVar(&opts.ExpandInstanceTypes, "expand-instance-types", false, "replace instance types by category, generation, CPU and memory requirements covering them")
+	cmd.Flags().BoolVar(&opts.MultiArch, "multi-arch", false, "launch amd64 and arm64 instances from NodePools of nodegroups whose AMI supports both architectures")
+	cmd.Flags().BoolVar(&opts.PinAMI, "pin-ami", false, "select the AMIs of the release version of managed nodegroups instead of the latest EKS optimized AMI")
+	cmd.Flags().BoolVar(&opts.SubnetTags, "subnet-tags", false, "select subnets by tags common to the subnets of each nodegroup instead of subnet IDs")
+	cmd.Flags().IntVar(&opts.Concurrency, "concurrency", DefaultConcurrency, "number of nodegroups and launch templates described at the same time")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 0, "time limit of AWS and Kubernetes API calls (e.g.: 5m), no limit when not set")
+	cmd.Flags().StringVar(&opts.Record, "record", "", "file to save AWS API requests and responses to, account IDs and access keys are redacted")
+	cmd.Flags().StringVar(&opts.Replay, "replay", "", "file saved with --record to serve AWS API responses from, AWS APIs are not called")
+	addDisruptionFlags(cmd, &opts)
 	_ = cmd.MarkFlagRequired("cluster")
 	_ = cmd.MarkFlagRequired("karpenter-nodegroup")
 	_ = cmd.Flags().MarkHidden("debug")
-	cmd.SetHelpFunc(usage)
 
 	return &opts
 }
 
 func (o *Options) Parse() error {
+	if !o.Apply && (o.Kubeconfig != "" || o.KubeContext != "") {
+		return fmt.Errorf(`"--kubeconfig" and "--context" flags are used only with "--apply" flag`)
+	}
+	if o.Apply && o.OutputDir != "" {
+		return fmt.Errorf(`"--apply" flag can not be used with "--output-dir" flag`)
+	}
+	if err := o.parseOutput(); err != nil {
+		return err
+	}
+	return o.parseGenerate()
+}
+
+func (o *Options) ParseDiff() error {
+	if o.LiveDir != "" && (o.Kubeconfig != "" || o.KubeContext != "") {
+		return fmt.Errorf(`"--live-dir" flag can not be used with "--kubeconfig" or "--context" flags`)
+	}
+	return o.parseGenerate()
+}
+
+func (o *Options) parseGenerate() error {
 	if o.ClusterName == "" {
 		return fmt.Errorf(`specify value for "--cluster" flag (e.g.: karpenter-generate --cluster <Cluster Name> --karpenter-nodegroup <Karpenter Nodegroup Name>)`)
 	}
 	if o.KarpenterNodegroupName == "" {
 		return fmt.Errorf(`specify value for "--karpenter-nodegroup" flag (e.g.: karpenter-generate --cluster <Cluster Name> --karpenter-nodegroup <Karpenter Nodegroup Name>)`)
 	}
+	if o.AutoScalingGroupTag != "" && !strings.Contains(o.AutoScalingGroupTag, "=") {
+		return fmt.Errorf(`invalid value for "--asg-tag" flag, specify tag as <key>=<value> (e.g.: kubernetes.io/cluster/<Cluster Name>=owned)`)
+	}
+	if o.TargetAMIFamily != "" && o.TargetAMIFamily != AMIFamilyAL2023 {
+		return fmt.Errorf(`invalid value for "--target-ami-family" flag, valid value is "AL2023"`)
+	}
+	if o.Record != "" && o.Replay != "" {
+		return fmt.Errorf(`"--record" flag can not be used with "--replay" flag`)
+	}
+	if o.InputDir != "" && (o.Record != "" || o.Replay != "") {
+		return fmt.Errorf(`"--input-dir" flag can not be used with "--record" or "--replay" flags, AWS APIs are not called`)
+	}
+	if err := o.parseLimitsHeadroom(); err != nil {
+		return err
+	}
+	if err := o.parseDisruption(); err != nil {
+		return err
+	}
+	if err := o.parseConcurrency(); err != nil {
+		return err
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf(`invalid value for "--timeout" flag, specify a positive duration (e.g.: 5m)`)
+	}
+	return o.parseAPIVersion()
+}
+
+// NewFromEksctl adds flags of the from-eksctl command
+func NewFromEksctl(cmd *cobra.Command) *Options {
+	opts := Options{}
+	cmd.Flags().StringVarP(&opts.ConfigFile, "config-file", "f", "", "eksctl ClusterConfig file")
+	cmd.Flags().StringVar(&opts.NodeRole, "role", "", "node IAM role for nodegroups without iam.instanceRoleARN or iam.instanceProfileARN")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "yaml", "output format (yaml, json, helm-chart or terraform)")
+	cmd.Flags().BoolVar(&opts.TerraformVariables, "terraform-variables", false, "replace subnet, security group and AMI IDs and roles by Terraform variables")
+	cmd.Flags().StringVar(&opts.APIVersion, "api-version", APIVersionV1beta1, "karpenter API version of generated resources (v1beta1 or v1)")
+	cmd.Flags().Float64Var(&opts.LimitsHeadroom, "limits-headroom", 1, "factor applied to the capacity of nodegroups (max size times the largest instance type) in NodePool limits")
+	cmd.Flags().BoolVar(&opts.ExpandInstanceTypes, "expand-instance-types", false, "replace instance types by category, generation, CPU and memory requirements covering them")
+	cmd.Flags().BoolVar(&opts.MultiArch, "multi-arch", false, "launch amd64 and arm64 instances from NodePools of nodegroups whose AMI supports both architectures")
+	addDisruptionFlags(cmd, &opts)
+	addOutputDirFlags(cmd, &opts)
+	_ = cmd.MarkFlagRequired("config-file")
+	cmd.SetHelpFunc(fromEksctlUsage)
+
+	return &opts
+}
+
+func (o *Options) ParseFromEksctl() error {
+	if o.ConfigFile == "" {
+		return fmt.Errorf(`specify value for "--config-file" flag (e.g.: karpenter-generate from-eksctl -f cluster.yaml)`)
+	}
+	if err := o.parseOutput(); err != nil {
+		return err
+	}
+	if err := o.parseLimitsHeadroom(); err != nil {
+		return err
+	}
+	if err := o.parseDisruption(); err != nil {
+		return err
+	}
+	return o.parseAPIVersion()
+}
+
+func (o *Options) parseOutput() error {
+	switch o.Output {
+	case "", "yaml", "json", OutputHelmChart, OutputTerraform:
+	default:
+		return fmt.Errorf(`invalid output type, valid values are "yaml", "json", "helm-chart" or "terraform"`)
+	}
+	if o.TerraformVariables && o.Output != OutputTerraform {
+		return fmt.Errorf(`"--terraform-variables" flag is used only with "--output terraform"`)
+	}
+	if o.Output == OutputTerraform && o.OutputDir != "" {
+		return fmt.Errorf(`"--output-dir" flag can not be used with "--output terraform"`)
+	}
+	if o.Overlays && o.OutputDir == "" {
+		return fmt.Errorf(`specify value for "--output-dir" flag to use "--overlays" flag`)
+	}
+	if o.Output != OutputHelmChart {
+		return nil
+	}
+	if o.OutputDir == "" {
+		return fmt.Errorf(`specify chart directory with "--output-dir" flag (e.g.: --output helm-chart --output-dir karpenter)`)
+	}
+	if o.Overlays {
+		return fmt.Errorf(`"--overlays" flag can not be used with "--output helm-chart"`)
+	}
+	return nil
+}
+
+func (o *Options) parseLimitsHeadroom() error {
+	switch {
+	case o.LimitsHeadroom == 0:
+		o.LimitsHeadroom = 1
+	case o.LimitsHeadroom < 1:
+		return fmt.Errorf(`invalid value for "--limits-headroom" flag, specify a factor greater than or equal to 1 (e.g.: 1.5)`)
+	}
+	return nil
+}
+
+func (o *Options) parseConcurrency() error {
+	switch {
+	case o.Concurrency == 0:
+		o.Concurrency = DefaultConcurrency
+	case o.Concurrency < 0:
+		return fmt.Errorf(`invalid value for "--concurrency" flag, specify a number greater than or equal to 1`)
+	}
+	return nil
+}
+
+func (o *Options) parseDisruption() error {
+	switch o.ConsolidationPolicy {
+	case "", "WhenEmpty", "WhenUnderutilized", "WhenEmptyOrUnderutilized":
+	default:
+		return fmt.Errorf(`invalid value for "--consolidation-policy" flag, valid values are "WhenEmpty", "WhenUnderutilized" or "WhenEmptyOrUnderutilized"`)
+	}
+	if err := parseNillableDuration("--consolidate-after", o.ConsolidateAfter); err != nil {
+		return err
+	}
+	return parseNillableDuration("--expire-after", o.ExpireAfter)
+}
+
+func parseNillableDuration(flag, value string) error {
+	if value == "" || value == "Never" {
+		return nil
+	}
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf(`invalid value for "%s" flag, specify a duration or "Never" (e.g.: 10m)`, flag)
+	}
+	return nil
+}
+
+func (o *Options) parseAPIVersion() error {
+	switch o.APIVersion {
+	case "":
+		o.APIVersion = APIVersionV1beta1
+	case APIVersionV1beta1, APIVersionV1:
+	default:
+		return fmt.Errorf(`invalid value for "--api-version" flag, valid values are "v1beta1" or "v1"`)
+	}
 	return nil
 }
 
@@ -53,6 +301,8 @@ Usage:
   karpenter-generate --cluster <Cluster Name> --karpenter-nodegroup <Karpenter Nodegroup Name>
 
 Available Commands:
+  diff        Compare generated resources with resources in the cluster
+  from-eksctl Generate Karpenter Custom Resources from eksctl ClusterConfig file
   version     Print the version and build information for karpenter-generate
 
 Flags:
@@ -66,9 +316,153 @@ Optiona Flags:
                        (default: AWS CLI configuration)
   --profile string     use the specific profile from your credential file 
                        (default: AWS CLI configuration)
-  --output string      output format (yaml or json)
-					   (default: yaml)
+  --output string      output format (yaml, json, helm-chart or terraform), helm-chart
+                       writes a chart to --output-dir (default: yaml)
+  --api-version string karpenter API version of generated resources (v1beta1 or v1)
+                       (default: v1beta1)
+  --input-dir string   directory with JSON output of "aws eks describe-cluster",
+                       "aws eks describe-nodegroup", "aws ec2 describe-launch-template-versions",
+                       "aws ec2 describe-subnets", "aws ec2 describe-instance-types",
+                       "aws ssm get-parameters" and "aws autoscaling describe-auto-scaling-groups",
+                       AWS APIs are not called
+  --record string      file to save AWS API requests and responses to, account IDs and
+                       access keys are redacted
+  --replay string      file saved with --record to serve AWS API responses from,
+                       AWS APIs are not called
+  --concurrency int    number of nodegroups and launch templates described at the
+                       same time, throttled requests are retried (default: 10)
+  --timeout duration   time limit of AWS and Kubernetes API calls (e.g.: 5m), calls are
+                       also cancelled by Ctrl-C (default: no limit)
+  --asg strings        names of self-managed Auto Scaling groups to convert
+  --asg-tag string     tag of self-managed Auto Scaling groups to convert
+                       (e.g.: kubernetes.io/cluster/<Cluster Name>=owned), Auto Scaling
+                       groups are only converted with --asg or --asg-tag
+  --target-ami-family string
+                       AMI family to move AL2 nodegroups to (AL2023), bootstrap.sh
+                       arguments are rewritten into a nodeadm NodeConfig
+  --limits-headroom float
+                       factor applied to max size times the largest instance type of
+                       nodegroups in NodePool limits (default: 1)
+  --expand-instance-types
+                       replace instance types of nodegroups by instance category, generation,
+                       CPU and memory requirements covering them, Spot NodePools require
+                       as many instance types as the nodegroup (up to 15)
+  --multi-arch         launch amd64 and arm64 instances from NodePools of nodegroups whose
+                       AMI supports both architectures (with --expand-instance-types)
+  --disruption-policy string
+                       YAML file with consolidationPolicy, consolidateAfter, expireAfter
+                       and budgets of all the NodePools and of NodePools of nodegroups
+  --consolidation-policy string
+                       consolidation policy of all the NodePools (WhenEmpty,
+                       WhenUnderutilized or WhenEmptyOrUnderutilized)
+  --consolidate-after string
+                       consolidateAfter of all the NodePools (e.g.: 10m or Never)
+  --expire-after string
+                       expireAfter of all the NodePools (e.g.: 720h or Never)
+  --pin-ami            select the AMIs of the release version of managed nodegroups
+                       instead of the latest EKS optimized AMI
+  --subnet-tags        select subnets by tags selecting exactly the subnets of each nodegroup
+                       (e.g.: karpenter.sh/discovery) instead of subnet IDs, subnet IDs are
+                       kept with a warning when no such tags exist
+  --apply              server-side apply generated resources to the cluster
+                       instead of printing them
+  --kubeconfig string  kubeconfig file used by --apply
+                       (default: $KUBECONFIG or ~/.kube/config, EKS cluster endpoint and
+                       IAM token of the AWS profile when there is no kubeconfig)
+  --context string     kubeconfig context used by --apply
+                       (default: current context of the kubeconfig, it must point at the
+                       endpoint of the EKS cluster)
+  --output-dir string  directory to write nodepools/<name>.yaml, ec2nodeclasses/<name>.yaml
+                       and a kustomization.yaml listing them to, instead of printing them
+  --overlays           write a kustomize overlay keeping only the resources of each
+                       source nodegroup to overlays/<nodegroup> of --output-dir
+  --terraform-variables
+                       replace subnet, security group and AMI IDs and roles of
+                       EC2NodeClasses by Terraform variables (with --output terraform)
   -h, --help           help for karpenter-generate
 	`
 	cmd.Println(usageString)
 }
+
+func fromEksctlUsage(cmd *cobra.Command, _ []string) {
+	usageString := `
+Description:
+  Generate Karpenter Custom Resources such as Nodepools and EC2NodeClass
+  from nodeGroups and managedNodeGroups of eksctl ClusterConfig file.
+  AWS APIs are not called.
+
+Usage:
+  karpenter-generate from-eksctl -f <ClusterConfig File>
+
+Flags:
+  -f, --config-file string   eksctl ClusterConfig file
+
+Optiona Flags:
+  --role string          node IAM role for nodegroups without iam.instanceRoleARN
+                         or iam.instanceProfileARN
+  --output string        output format (yaml, json, helm-chart or terraform), helm-chart
+                         writes a chart to --output-dir (default: yaml)
+  --api-version string   karpenter API version of generated resources (v1beta1 or v1)
+                         (default: v1beta1)
+  --limits-headroom float
+                         factor applied to maxSize times the largest instance type of
+                         nodegroups in NodePool limits (default: 1)
+  --expand-instance-types
+                         replace instance types of nodegroups by instance category, generation,
+                         CPU and memory requirements covering them, Spot NodePools require
+                         as many instance types as the nodegroup (up to 15)
+  --multi-arch           launch amd64 and arm64 instances from NodePools of nodegroups whose
+                         AMI supports both architectures (with --expand-instance-types)
+  --disruption-policy string
+                         YAML file with consolidationPolicy, consolidateAfter, expireAfter
+                         and budgets of all the NodePools and of NodePools of nodegroups
+  --consolidation-policy string
+                         consolidation policy of all the NodePools (WhenEmpty,
+                         WhenUnderutilized or WhenEmptyOrUnderutilized)
+  --consolidate-after string
+                         consolidateAfter of all the NodePools (e.g.: 10m or Never)
+  --expire-after string
+                         expireAfter of all the NodePools (e.g.: 720h or Never)
+  --output-dir string    directory to write nodepools/<name>.yaml, ec2nodeclasses/<name>.yaml
+                         and a kustomization.yaml listing them to, instead of printing them
+  --overlays             write a kustomize overlay keeping only the resources of each
+                         source nodegroup to overlays/<nodegroup> of --output-dir
+  --terraform-variables  replace subnet, security group and AMI IDs and roles of
+                         EC2NodeClasses by Terraform variables (with --output terraform)
+  -h, --help             help for from-eksctl
+	`
+	cmd.Println(usageString)
+}
+
+func diffUsage(cmd *cobra.Command, _ []string) {
+	usageString := `
+Description:
+  Compare generated Karpenter Custom Resources with NodePools and EC2NodeClasses
+  in the cluster. Exits with code 2 when they differ and with code 1 on errors.
+
+Usage:
+  karpenter-generate diff --cluster <Cluster Name> --karpenter-nodegroup <Karpenter Nodegroup Name>
+
+Flags:
+  --cluster string               name of the EKS cluster
+  --karpenter-nodegroup string   name of the EKS managed nodegroup running Karpenter deployment or fargate
+
+Optiona Flags:
+  --live-dir string    directory with manifests of live resources (e.g.: output of
+                       "kubectl get nodepools,ec2nodeclasses -o yaml"), the cluster is not called
+  --kubeconfig string  kubeconfig file of the cluster
+                       (default: $KUBECONFIG or ~/.kube/config, EKS cluster endpoint and
+                       IAM token of the AWS profile when there is no kubeconfig)
+  --context string     kubeconfig context of the cluster
+                       (default: current context of the kubeconfig, it must point at the
+                       endpoint of the EKS cluster)
+
+  All the flags of karpenter-generate selecting nodegroups are supported
+  (--nodegroup, --region, --profile, --api-version, --input-dir, --asg,
+  --asg-tag, --target-ami-family, --limits-headroom, --expand-instance-types, --multi-arch,
+  --pin-ami, --subnet-tags, --record, --replay, --concurrency, --timeout,
+  --disruption-policy, --consolidation-policy, --consolidate-after and --expire-after)
+  -h, --help           help for diff
+	`
+	cmd.Println(usageString)
+}
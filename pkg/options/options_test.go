@@ -2,6 +2,7 @@ package options
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -36,6 +37,211 @@ func TestParse(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Valid api version",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				APIVersion:             APIVersionV1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid api version",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				APIVersion:             "v1alpha5",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Valid target AMI family",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				TargetAMIFamily:        AMIFamilyAL2023,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid target AMI family",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				TargetAMIFamily:        "Bottlerocket",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Apply with kubeconfig context",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Apply:                  true,
+				Kubeconfig:             "kubeconfig",
+				KubeContext:            "my-context",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Kubeconfig without apply",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Kubeconfig:             "kubeconfig",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Context of the default kubeconfig",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Apply:                  true,
+				KubeContext:            "my-context",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Apply with output directory",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Apply:                  true,
+				OutputDir:              "out",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Overlays without output directory",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Overlays:               true,
+			},
+			wantErr: true,
+		}, {
+			name: "Helm chart with output directory",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Output:                 OutputHelmChart,
+				OutputDir:              "karpenter",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Helm chart without output directory",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Output:                 OutputHelmChart,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid output",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Output:                 "toml",
+			},
+			wantErr: true,
+		}, {
+			name: "Terraform variables",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Output:                 OutputTerraform,
+				TerraformVariables:     true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid consolidation policy",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				ConsolidationPolicy:    "Always",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid consolidate after",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				ConsolidateAfter:       "ten minutes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Expire after never",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				ExpireAfter:            "Never",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Limits headroom lower than 1",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				LimitsHeadroom:         0.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative concurrency",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Concurrency:            -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative timeout",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Timeout:                -time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Record with replay",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Record:                 "recording.json",
+				Replay:                 "recording.json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Replay with input directory",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Replay:                 "recording.json",
+				InputDir:               "input",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Terraform variables without terraform output",
+			opts: &Options{
+				ClusterName:            "my-cluster",
+				KarpenterNodegroupName: "my-karpenter-nodegroup",
+				Output:                 "yaml",
+				TerraformVariables:     true,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -49,3 +255,43 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFromEksctl(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *Options
+		wantErr bool
+	}{
+		{
+			name: "Valid options",
+			opts: &Options{
+				ConfigFile: "cluster.yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Missing config file",
+			opts:    &Options{},
+			wantErr: true,
+		},
+		{
+			name: "Invalid api version",
+			opts: &Options{
+				ConfigFile: "cluster.yaml",
+				APIVersion: "v1alpha5",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.ParseFromEksctl()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
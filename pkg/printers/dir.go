@@ -0,0 +1,164 @@
+package printers
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	KustomizationFile = "kustomization.yaml"
+	OverlaysDir       = "overlays"
+)
+
+// Directories of the Karpenter kinds which are generated
+var kindDirs = map[string]string{
+	"NodePool":     "nodepools",
+	"EC2NodeClass": "ec2nodeclasses",
+}
+
+// Annotations holding the nodegroups a resource is generated from
+var sourceAnnotations = []string{
+	"migrate.karpenter.sh/source-nodegroup",
+	"migrate.karpenter.sh/merged-nodepools",
+	"migrate.karpenter.sh/merged-nodeclasses",
+}
+
+type kustomization struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Resources  []string             `json:"resources"`
+	Patches    []kustomizationPatch `json:"patches,omitempty"`
+}
+
+type kustomizationPatch struct {
+	Patch string `json:"patch"`
+}
+
+// resourceFile is a generated resource and its path relative to the output directory
+type resourceFile struct {
+	obj     runtime.Object
+	path    string
+	sources []string
+}
+
+// WriteDir writes every resource to <kind>/<name>.<format> in dir with a kustomization.yaml listing them,
+// with overlays an overlay keeping only the resources of each source nodegroup is written to overlays/<nodegroup>
+func WriteDir(dir string, format Output, objs []runtime.Object, overlays bool) error {
+	files := []resourceFile{}
+	for _, obj := range objs {
+		file, err := newResourceFile(obj, format)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+
+	for _, file := range files {
+		if err := writeResource(filepath.Join(dir, filepath.FromSlash(file.path)), format, file.obj); err != nil {
+			return err
+		}
+	}
+	err := writeKustomization(dir, kustomization{
+		Resources: lo.Map(files, func(file resourceFile, _ int) string { return file.path }),
+	})
+	if err != nil || !overlays {
+		return err
+	}
+
+	sources := lo.Uniq(lo.FlatMap(files, func(file resourceFile, _ int) []string { return file.sources }))
+	sort.Strings(sources)
+	for _, source := range sources {
+		overlay := kustomization{Resources: []string{"../.."}}
+		for _, file := range files {
+			if lo.Contains(file.sources, source) {
+				continue
+			}
+			patch, err := deletePatch(file.obj)
+			if err != nil {
+				return err
+			}
+			overlay.Patches = append(overlay.Patches, kustomizationPatch{Patch: patch})
+		}
+		if err := writeKustomization(filepath.Join(dir, OverlaysDir, source), overlay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newResourceFile(obj runtime.Object, format Output) (resourceFile, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return resourceFile{}, err
+	}
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	kindDir, ok := kindDirs[kind]
+	if !ok {
+		return resourceFile{}, fmt.Errorf(`unsupported kind "%s"`, kind)
+	}
+
+	sources := []string{}
+	for _, annotation := range sourceAnnotations {
+		if val := accessor.GetAnnotations()[annotation]; val != "" {
+			sources = append(sources, strings.Split(val, ",")...)
+		}
+	}
+	return resourceFile{
+		obj:     obj,
+		path:    path.Join(kindDir, accessor.GetName()+"."+string(format)),
+		sources: lo.Uniq(sources),
+	}, nil
+}
+
+func writeResource(file string, format Output, obj runtime.Object) error {
+	printer, err := NewPrinter(format)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(file) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return printer.PrintObj(obj, f)
+}
+
+func writeKustomization(dir string, k kustomization) error {
+	k.APIVersion, k.Kind = "kustomize.config.k8s.io/v1beta1", "Kustomization"
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, KustomizationFile), data, 0o644) // #nosec G306
+}
+
+// Returns a strategic merge patch removing obj from the kustomization
+func deletePatch(obj runtime.Object) (string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+	data, err := yaml.Marshal(map[string]any{
+		"$patch":     "delete",
+		"apiVersion": obj.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+		"kind":       obj.GetObjectKind().GroupVersionKind().Kind,
+		"metadata":   map[string]string{"name": accessor.GetName()},
+	})
+	return string(data), err
+}
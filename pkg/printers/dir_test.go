@@ -0,0 +1,72 @@
+package printers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+	objs := []runtime.Object{
+		&sigkarpenter.NodePool{
+			TypeMeta: metav1.TypeMeta{APIVersion: "karpenter.sh/v1beta1", Kind: "NodePool"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-a", Annotations: map[string]string{
+				"migrate.karpenter.sh/source-nodegroup": "ng-a",
+				"migrate.karpenter.sh/merged-nodepools": "ng-b",
+			}},
+		},
+		&awskarpenter.EC2NodeClass{
+			TypeMeta: metav1.TypeMeta{APIVersion: "karpenter.k8s.aws/v1beta1", Kind: "EC2NodeClass"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-a", Annotations: map[string]string{
+				"migrate.karpenter.sh/source-nodegroup": "ng-a",
+			}},
+		},
+	}
+
+	require.NoError(t, WriteDir(dir, YAML, objs, true))
+
+	nodePool, err := os.ReadFile(filepath.Join(dir, "nodepools", "ng-a.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(nodePool), "kind: NodePool\n")
+	assert.NotContains(t, string(nodePool), "---")
+
+	kustomization, err := os.ReadFile(filepath.Join(dir, KustomizationFile))
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ec2nodeclasses/ng-a.yaml
+- nodepools/ng-a.yaml
+`, string(kustomization))
+
+	overlayA, err := os.ReadFile(filepath.Join(dir, OverlaysDir, "ng-a", KustomizationFile))
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ../..
+`, string(overlayA))
+
+	overlayB, err := os.ReadFile(filepath.Join(dir, OverlaysDir, "ng-b", KustomizationFile))
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+patches:
+- patch: |
+    $patch: delete
+    apiVersion: karpenter.k8s.aws/v1beta1
+    kind: EC2NodeClass
+    metadata:
+      name: ng-a
+resources:
+- ../..
+`, string(overlayB))
+}
@@ -0,0 +1,193 @@
+package printers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	HelmChartFile  = "Chart.yaml"
+	HelmValuesFile = "values.yaml"
+	HelmTemplates  = "templates"
+)
+
+// knob is a field of generated resources exposed in values.yaml
+type knob struct {
+	name string
+	path []string
+	// Key of the requirement holding the values when the field is a requirement
+	requirement string
+	empty       any
+}
+
+var nodePoolKnobs = []knob{
+	{name: "instanceTypes", path: []string{"spec", "template", "spec", "requirements"}, requirement: "node.kubernetes.io/instance-type"},
+	{name: "capacityTypes", path: []string{"spec", "template", "spec", "requirements"}, requirement: "karpenter.sh/capacity-type"},
+	{name: "limits", path: []string{"spec", "limits"}, empty: map[string]any{}},
+	{name: "disruption", path: []string{"spec", "disruption"}, empty: map[string]any{}},
+	{name: "taints", path: []string{"spec", "template", "spec", "taints"}, empty: []any{}},
+	{name: "labels", path: []string{"spec", "template", "metadata", "labels"}, empty: map[string]any{}},
+}
+
+var nodeClassKnobs = []knob{
+	{name: "role", path: []string{"spec", "role"}, empty: ""},
+	{name: "subnetSelectorTerms", path: []string{"spec", "subnetSelectorTerms"}, empty: []any{}},
+	{name: "securityGroupSelectorTerms", path: []string{"spec", "securityGroupSelectorTerms"}, empty: []any{}},
+	{name: "amiSelectorTerms", path: []string{"spec", "amiSelectorTerms"}, empty: []any{}},
+	{name: "tags", path: []string{"spec", "tags"}, empty: map[string]any{}},
+}
+
+// Values keys of the Karpenter kinds which are generated
+var valuesKeys = map[string]string{
+	"NodePool":     "nodePools",
+	"EC2NodeClass": "ec2NodeClasses",
+}
+
+var knobPlaceholder = regexp.MustCompile(`(?m)^( *)([A-Za-z]+): __knob_([A-Za-z]+)__$`)
+
+// WriteHelmChart writes a Helm chart to dir rendering the generated resources with default values,
+// knobs of every NodePool and EC2NodeClass are exposed in values.yaml under their name
+func WriteHelmChart(dir string, objs []runtime.Object) error {
+	values := map[string]map[string]any{"nodePools": {}, "ec2NodeClasses": {}}
+	templates := map[string]string{}
+	for _, obj := range objs {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		u := &unstructured.Unstructured{Object: content}
+		unstructured.RemoveNestedField(u.Object, "status")
+		unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+
+		valuesKey, ok := valuesKeys[u.GetKind()]
+		if !ok {
+			return fmt.Errorf(`unsupported kind "%s"`, u.GetKind())
+		}
+		knobs := nodePoolKnobs
+		if u.GetKind() == "EC2NodeClass" {
+			knobs = nodeClassKnobs
+		}
+
+		objValues, err := extractKnobs(u, knobs)
+		if err != nil {
+			return err
+		}
+		values[valuesKey][u.GetName()] = objValues
+
+		data, err := yaml.Marshal(u.Object)
+		if err != nil {
+			return err
+		}
+		// Braces in generated content such as user data are not template actions
+		escaped := strings.ReplaceAll(string(data), "{{", `{{"{{"}}`)
+		header := fmt.Sprintf("{{- $values := index .Values.%s %q }}\n", valuesKey, u.GetName())
+		templates[kindDirs[u.GetKind()]+"-"+u.GetName()+".yaml"] = header + renderKnobs(escaped, objValues)
+	}
+
+	chart, err := yaml.Marshal(map[string]any{
+		"apiVersion":  "v2",
+		"name":        filepath.Base(dir),
+		"description": "Karpenter NodePools and EC2NodeClasses generated by karpenter-generate",
+		"type":        "application",
+		"version":     "0.1.0",
+	})
+	if err != nil {
+		return err
+	}
+	valuesData, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		HelmChartFile:  string(chart),
+		HelmValuesFile: string(valuesData),
+	}
+	for name, template := range templates {
+		files[filepath.Join(HelmTemplates, name)] = template
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(files[name]), 0o644); err != nil { // #nosec G306
+			return err
+		}
+	}
+	return nil
+}
+
+// Moves the knobs of u into the returned values, knob fields of u are replaced by placeholders
+func extractKnobs(u *unstructured.Unstructured, knobs []knob) (map[string]any, error) {
+	values := map[string]any{}
+	placeholder := func(k knob) string { return "__knob_" + k.name + "__" }
+	for _, k := range knobs {
+		if k.requirement == "" {
+			val, found, err := unstructured.NestedFieldNoCopy(u.Object, k.path...)
+			if err != nil {
+				return nil, err
+			}
+			values[k.name] = k.empty
+			if found && val != nil {
+				values[k.name] = val
+			}
+			if err := unstructured.SetNestedField(u.Object, placeholder(k), k.path...); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Requirements without values are invalid, the knob is only exposed when the requirement is generated
+		requirements, _, err := unstructured.NestedSlice(u.Object, k.path...)
+		if err != nil {
+			return nil, err
+		}
+		for _, requirement := range requirements {
+			req, ok := requirement.(map[string]any)
+			if !ok || req["key"] != k.requirement {
+				continue
+			}
+			values[k.name] = req["values"]
+			req["values"] = placeholder(k)
+		}
+		if err := unstructured.SetNestedSlice(u.Object, requirements, k.path...); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Replaces placeholders with templates of the values, empty values are omitted like the generator does
+func renderKnobs(data string, values map[string]any) string {
+	return knobPlaceholder.ReplaceAllStringFunc(data, func(line string) string {
+		match := knobPlaceholder.FindStringSubmatch(line)
+		indent, key, name := match[1], match[2], match[3]
+		// Lists are not indented under their key
+		nindent := len(indent) + 2
+		if _, isList := values[name].([]any); isList {
+			nindent = len(indent)
+		}
+
+		if key == "values" {
+			return fmt.Sprintf("%s%s:\n{{- toYaml $values.%s | nindent %d }}", indent, key, name, nindent)
+		}
+		if _, isString := values[name].(string); isString {
+			return fmt.Sprintf("{{- with $values.%s }}\n%s%s: {{ toYaml . }}\n{{- end }}", name, indent, key)
+		}
+		return fmt.Sprintf("{{- with $values.%s }}\n%s%s:\n  {{- toYaml . | nindent %d }}\n{{- end }}", name, indent, key, nindent)
+	})
+}
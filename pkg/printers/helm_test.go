@@ -0,0 +1,136 @@
+package printers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+// Functions of Helm used by the chart templates
+var helmFuncs = template.FuncMap{
+	"toYaml": func(v any) string {
+		data, _ := yaml.Marshal(v)
+		return strings.TrimSuffix(string(data), "\n")
+	},
+	"nindent": func(spaces int, v string) string {
+		pad := strings.Repeat(" ", spaces)
+		return "\n" + pad + strings.ReplaceAll(v, "\n", "\n"+pad)
+	},
+}
+
+func helmObjects() []runtime.Object {
+	return []runtime.Object{
+		&sigkarpenter.NodePool{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "karpenter.sh/v1beta1", Kind: "NodePool"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-a"},
+			Spec: sigkarpenter.NodePoolSpec{
+				Limits: sigkarpenter.Limits{corev1.ResourceCPU: resource.MustParse("100")},
+				Template: sigkarpenter.NodeClaimTemplate{
+					Spec: sigkarpenter.NodeClaimSpec{
+						NodeClassRef: &sigkarpenter.NodeClassReference{Name: "ng-a"},
+						Requirements: []sigkarpenter.NodeSelectorRequirementWithMinValues{
+							{NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+								Key: corev1.LabelInstanceTypeStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"m5.large"},
+							}},
+						},
+						Taints: []corev1.Taint{{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoSchedule}},
+					},
+				},
+			},
+		},
+		&awskarpenter.EC2NodeClass{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "karpenter.k8s.aws/v1beta1", Kind: "EC2NodeClass"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-a"},
+			Spec: awskarpenter.EC2NodeClassSpec{
+				AMIFamily:           lo.ToPtr(awskarpenter.AMIFamilyAL2),
+				Role:                "eks-node-role",
+				SubnetSelectorTerms: []awskarpenter.SubnetSelectorTerm{{ID: "subnet-1"}},
+				SecurityGroupSelectorTerms: []awskarpenter.SecurityGroupSelectorTerm{
+					{Tags: map[string]string{"kubernetes.io/cluster/my-cluster": "owned"}},
+				},
+				UserData: lo.ToPtr("echo {{ not a template }}"),
+			},
+		},
+	}
+}
+
+// Renders every template of the chart with values and returns the rendered documents
+func renderChart(t *testing.T, dir string, values map[string]any) []map[string]any {
+	files, err := filepath.Glob(filepath.Join(dir, HelmTemplates, "*.yaml"))
+	require.NoError(t, err)
+
+	docs := []map[string]any{}
+	for _, file := range files {
+		tmpl, err := template.New(filepath.Base(file)).Funcs(helmFuncs).ParseFiles(file)
+		require.NoError(t, err)
+		out := &bytes.Buffer{}
+		require.NoError(t, tmpl.Execute(out, map[string]any{"Values": values}))
+
+		doc := map[string]any{}
+		require.NoError(t, yaml.Unmarshal(out.Bytes(), &doc), out.String())
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestWriteHelmChart(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "karpenter")
+	require.NoError(t, WriteHelmChart(dir, helmObjects()))
+
+	chart, err := os.ReadFile(filepath.Join(dir, HelmChartFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(chart), "name: karpenter\n")
+
+	data, err := os.ReadFile(filepath.Join(dir, HelmValuesFile))
+	require.NoError(t, err)
+	values := map[string]any{}
+	require.NoError(t, yaml.Unmarshal(data, &values))
+	nodePoolValues := values["nodePools"].(map[string]any)["ng-a"].(map[string]any)
+	assert.Equal(t, []any{"m5.large"}, nodePoolValues["instanceTypes"])
+	assert.Equal(t, map[string]any{}, nodePoolValues["labels"])
+	assert.Equal(t, "eks-node-role", values["ec2NodeClasses"].(map[string]any)["ng-a"].(map[string]any)["role"])
+
+	// Default values render the generated resources
+	expected := []map[string]any{}
+	for _, obj := range helmObjects() {
+		data, err := yaml.Marshal(obj)
+		require.NoError(t, err)
+		doc := map[string]any{}
+		require.NoError(t, yaml.Unmarshal(data, &doc))
+		delete(doc["metadata"].(map[string]any), "creationTimestamp")
+		delete(doc, "status")
+		expected = append(expected, doc)
+	}
+	docs := renderChart(t, dir, values)
+	// Templates are sorted by file name, ec2nodeclasses first
+	assert.Equal(t, expected[1], docs[0])
+	nodePool := docs[1]
+	// Labels are empty so the template metadata is rendered empty
+	expectedTemplate := expected[0]["spec"].(map[string]any)["template"].(map[string]any)
+	expectedTemplate["metadata"] = nil
+	assert.Equal(t, expected[0], nodePool)
+
+	// Values override the generated fields
+	nodePoolValues["instanceTypes"] = []any{"m6i.large", "m7i.large"}
+	nodePoolValues["taints"] = []any{}
+	nodePoolValues["labels"] = map[string]any{"team": "web"}
+	nodePool = renderChart(t, dir, values)[1]
+	spec := nodePool["spec"].(map[string]any)["template"].(map[string]any)
+	assert.Equal(t, map[string]any{"labels": map[string]any{"team": "web"}}, spec["metadata"])
+	assert.Equal(t, []any{"m6i.large", "m7i.large"}, spec["spec"].(map[string]any)["requirements"].([]any)[0].(map[string]any)["values"])
+	assert.NotContains(t, spec["spec"], "taints")
+}
@@ -4,9 +4,8 @@ import (
 	"fmt"
 	"os"
 
-	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/printers"
-	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
 )
 
 type Output string
@@ -18,6 +17,9 @@ type OutputPrinter struct {
 const (
 	YAML Output = "yaml"
 	JSON Output = "json"
+	// Written to the output directory by WriteHelmChart
+	HelmChart Output = "helm-chart"
+	Terraform Output = "terraform"
 )
 
 func NewPrinter(format Output) (printers.ResourcePrinter, error) {
@@ -26,25 +28,16 @@ func NewPrinter(format Output) (printers.ResourcePrinter, error) {
 		return &printers.YAMLPrinter{}, nil
 	case JSON:
 		return &printers.JSONPrinter{}, nil
+	case Terraform:
+		return NewTerraformPrinter(false), nil
 	default:
-		return nil, fmt.Errorf(`invalid output type, valid values are "yaml" or "json"`)
+		return nil, fmt.Errorf(`invalid output type, valid values are "yaml", "json" or "terraform"`)
 	}
 }
 
-func Print(p printers.ResourcePrinter, nodePools []sigkarpenter.NodePool, nodeClasses []awskarpenter.EC2NodeClass) error {
-
-	for _, np := range nodePools {
-		// #nosec G601
-		err := p.PrintObj(&np, os.Stdout)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, nc := range nodeClasses {
-		// #nosec G601
-		err := p.PrintObj(&nc, os.Stdout)
-		if err != nil {
+func Print(p printers.ResourcePrinter, objs []runtime.Object) error {
+	for _, obj := range objs {
+		if err := p.PrintObj(obj, os.Stdout); err != nil {
 			return err
 		}
 	}
@@ -27,6 +27,12 @@ func TestNewPrinter(t *testing.T) {
 			expected: &printers.JSONPrinter{},
 			wantErr:  false,
 		},
+		{
+			name:     "Terraform Printer",
+			format:   Terraform,
+			expected: &TerraformPrinter{},
+			wantErr:  false,
+		},
 		{
 			name:     "Invalid Format",
 			format:   "invalid",
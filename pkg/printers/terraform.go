@@ -0,0 +1,244 @@
+package printers
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var (
+	hclIdentifier   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	hclInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// hclExpression is rendered as-is instead of as a string (e.g.: var.role)
+type hclExpression string
+
+// terraformVariable is an input variable replacing AWS IDs of an EC2NodeClass
+type terraformVariable struct {
+	name        string
+	description string
+	varType     string
+	defaultVal  any
+}
+
+// TerraformPrinter prints resources as kubernetes_manifest resources of the Terraform Kubernetes provider,
+// with Variables subnet, security group and AMI IDs and the role of EC2NodeClasses are replaced by variables
+type TerraformPrinter struct {
+	Variables bool
+	printed   int
+}
+
+func NewTerraformPrinter(variables bool) *TerraformPrinter {
+	return &TerraformPrinter{Variables: variables}
+}
+
+func (p *TerraformPrinter) PrintObj(obj runtime.Object, w io.Writer) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	unstructured.RemoveNestedField(u.Object, "status")
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+
+	variables := []terraformVariable{}
+	if p.Variables && u.GetKind() == "EC2NodeClass" {
+		variables = replaceIDs(u)
+	}
+
+	out := &strings.Builder{}
+	if p.printed > 0 {
+		out.WriteString("\n")
+	}
+	p.printed++
+	for _, variable := range variables {
+		fmt.Fprintf(out, "variable %q {\n", variable.name)
+		writeHCLAttributes(out, map[string]any{
+			"description": variable.description,
+			"type":        hclExpression(variable.varType),
+			"default":     variable.defaultVal,
+		}, []string{"description", "type", "default"}, 1)
+		out.WriteString("}\n\n")
+	}
+	fmt.Fprintf(out, "resource \"kubernetes_manifest\" %q {\n", terraformName(strings.ToLower(u.GetKind())+"_"+u.GetName()))
+	writeHCLAttributes(out, map[string]any{"manifest": u.Object}, []string{"manifest"}, 1)
+	out.WriteString("}\n")
+
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+// Replaces IDs of selector terms and the role with variable references and returns the variables
+func replaceIDs(u *unstructured.Unstructured) []terraformVariable {
+	prefix := terraformName(u.GetName())
+	variables := []terraformVariable{}
+	spec, ok := u.Object["spec"].(map[string]any)
+	if !ok {
+		return variables
+	}
+	for _, field := range []struct{ key, variable, description string }{
+		{"subnetSelectorTerms", "subnet_ids", "Subnet IDs"},
+		{"securityGroupSelectorTerms", "security_group_ids", "Security group IDs"},
+		{"amiSelectorTerms", "ami_ids", "AMI IDs"},
+	} {
+		terms, _ := spec[field.key].([]any)
+		ids := []any{}
+		otherTerms := []any{}
+		for _, term := range terms {
+			if t, _ := term.(map[string]any); len(t) == 1 && t["id"] != nil {
+				ids = append(ids, t["id"])
+				continue
+			}
+			otherTerms = append(otherTerms, term)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		name := prefix + "_" + field.variable
+		variables = append(variables, terraformVariable{
+			name:        name,
+			description: fmt.Sprintf("%s of EC2NodeClass %s", field.description, u.GetName()),
+			varType:     "list(string)",
+			defaultVal:  ids,
+		})
+		expression := fmt.Sprintf("[for id in var.%s : { id = id }]", name)
+		if len(otherTerms) > 0 {
+			expression = fmt.Sprintf("concat(%s, %s)", expression, hclValue(otherTerms, 3))
+		}
+		spec[field.key] = hclExpression(expression)
+	}
+
+	if role, _ := spec["role"].(string); role != "" {
+		name := prefix + "_role"
+		variables = append(variables, terraformVariable{
+			name:        name,
+			description: fmt.Sprintf("Node IAM role of EC2NodeClass %s", u.GetName()),
+			varType:     "string",
+			defaultVal:  role,
+		})
+		spec["role"] = hclExpression("var." + name)
+	}
+	return variables
+}
+
+// Returns a valid Terraform resource or variable name
+func terraformName(name string) string {
+	name = hclInvalidChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// Writes attributes in the order of keys, equals signs of consecutive single-line attributes are aligned like "terraform fmt"
+func writeHCLAttributes(out *strings.Builder, attributes map[string]any, keys []string, depth int) {
+	indent := strings.Repeat("  ", depth)
+	rendered := lo.Map(keys, func(key string, _ int) string { return hclValue(attributes[key], depth) })
+	for idx := 0; idx < len(keys); {
+		// Group of consecutive single-line attributes
+		end := idx + 1
+		if !strings.Contains(rendered[idx], "\n") {
+			for end < len(keys) && !strings.Contains(rendered[end], "\n") {
+				end++
+			}
+		}
+		width := lo.Max(lo.Map(keys[idx:end], func(key string, _ int) int { return len(hclKey(key)) }))
+		for ; idx < end; idx++ {
+			fmt.Fprintf(out, "%s%-*s = %s\n", indent, width, hclKey(keys[idx]), rendered[idx])
+		}
+	}
+}
+
+func hclKey(key string) string {
+	if hclIdentifier.MatchString(key) {
+		return key
+	}
+	return hclString(key)
+}
+
+// Renders a decoded JSON value as an HCL expression nested at depth
+func hclValue(val any, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	switch v := val.(type) {
+	case nil:
+		return "null"
+	case hclExpression:
+		return string(v)
+	case string:
+		return hclString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		if len(v) == 0 {
+			return "[]"
+		}
+		items := lo.Map(v, func(item any, _ int) string { return hclValue(item, depth+1) })
+		if lo.NoneBy(items, func(item string) bool { return strings.Contains(item, "\n") }) {
+			if _, isMap := v[0].(map[string]any); !isMap {
+				return "[" + strings.Join(items, ", ") + "]"
+			}
+		}
+		out := &strings.Builder{}
+		out.WriteString("[\n")
+		for _, item := range items {
+			fmt.Fprintf(out, "%s  %s,\n", indent, item)
+		}
+		out.WriteString(indent + "]")
+		return out.String()
+	case map[string]any:
+		// Null attributes are omitted, they are pruned by the API server
+		keys := lo.Filter(lo.Keys(v), func(key string, _ int) bool { return v[key] != nil })
+		if len(keys) == 0 {
+			return "{}"
+		}
+		sort.Strings(keys)
+		out := &strings.Builder{}
+		out.WriteString("{\n")
+		writeHCLAttributes(out, v, keys, depth+1)
+		out.WriteString(indent + "}")
+		return out.String()
+	default:
+		return hclString(fmt.Sprint(v))
+	}
+}
+
+// Quotes s as an HCL string, template sequences are escaped
+func hclString(s string) string {
+	out := &strings.Builder{}
+	out.WriteString(`"`)
+	for idx, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteRune('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(out, `\u%04x`, r)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[idx+1:], "{"):
+			out.WriteRune(r)
+			out.WriteRune(r)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteString(`"`)
+	return out.String()
+}
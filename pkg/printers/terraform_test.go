@@ -0,0 +1,113 @@
+package printers
+
+import (
+	"bytes"
+	"testing"
+
+	awskarpenter "github.com/aws/karpenter-provider-aws/pkg/apis/v1beta1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigkarpenter "sigs.k8s.io/karpenter/pkg/apis/v1beta1"
+)
+
+func TestTerraformPrinter(t *testing.T) {
+	objs := []runtime.Object{
+		&sigkarpenter.NodePool{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "karpenter.sh/v1beta1", Kind: "NodePool"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-a"},
+			Spec: sigkarpenter.NodePoolSpec{
+				Template: sigkarpenter.NodeClaimTemplate{
+					Spec: sigkarpenter.NodeClaimSpec{
+						NodeClassRef: &sigkarpenter.NodeClassReference{Name: "ng-a"},
+					},
+				},
+			},
+		},
+		&awskarpenter.EC2NodeClass{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "karpenter.k8s.aws/v1beta1", Kind: "EC2NodeClass"},
+			ObjectMeta: metav1.ObjectMeta{Name: "ng-a"},
+			Spec: awskarpenter.EC2NodeClassSpec{
+				Role: "eks-node-role",
+				SubnetSelectorTerms: []awskarpenter.SubnetSelectorTerm{
+					{ID: "subnet-1"},
+					{Tags: map[string]string{"kubernetes.io/role/internal-elb": "1"}},
+				},
+				SecurityGroupSelectorTerms: []awskarpenter.SecurityGroupSelectorTerm{{ID: "sg-1"}},
+				UserData:                   lo.ToPtr("echo \"${HOME}\"\n"),
+			},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	printer := NewTerraformPrinter(true)
+	for _, obj := range objs {
+		require.NoError(t, printer.PrintObj(obj, out))
+	}
+	assert.Equal(t, `resource "kubernetes_manifest" "nodepool_ng_a" {
+  manifest = {
+    apiVersion = "karpenter.sh/v1beta1"
+    kind       = "NodePool"
+    metadata = {
+      name = "ng-a"
+    }
+    spec = {
+      disruption = {
+        expireAfter = "Never"
+      }
+      template = {
+        metadata = {}
+        spec = {
+          nodeClassRef = {
+            name = "ng-a"
+          }
+          resources = {}
+        }
+      }
+    }
+  }
+}
+
+variable "ng_a_subnet_ids" {
+  description = "Subnet IDs of EC2NodeClass ng-a"
+  type        = list(string)
+  default     = ["subnet-1"]
+}
+
+variable "ng_a_security_group_ids" {
+  description = "Security group IDs of EC2NodeClass ng-a"
+  type        = list(string)
+  default     = ["sg-1"]
+}
+
+variable "ng_a_role" {
+  description = "Node IAM role of EC2NodeClass ng-a"
+  type        = string
+  default     = "eks-node-role"
+}
+
+resource "kubernetes_manifest" "ec2nodeclass_ng_a" {
+  manifest = {
+    apiVersion = "karpenter.k8s.aws/v1beta1"
+    kind       = "EC2NodeClass"
+    metadata = {
+      name = "ng-a"
+    }
+    spec = {
+      role                       = var.ng_a_role
+      securityGroupSelectorTerms = [for id in var.ng_a_security_group_ids : { id = id }]
+      subnetSelectorTerms = concat([for id in var.ng_a_subnet_ids : { id = id }], [
+        {
+          tags = {
+            "kubernetes.io/role/internal-elb" = "1"
+          }
+        },
+      ])
+      userData = "echo \"$${HOME}\"\n"
+    }
+  }
+}
+`, out.String())
+}
@@ -0,0 +1,15 @@
+package warnings
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output is stderr so that warnings do not end up in generated manifests
+var Output io.Writer = os.Stderr
+
+// Warnf prints a warning about settings which could not be migrated as-is
+func Warnf(format string, a ...any) {
+	fmt.Fprintf(Output, "WARNING: "+format+"\n", a...)
+}